@@ -0,0 +1,293 @@
+package walker
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/foomo/walker/config"
+	"github.com/foomo/walker/vo"
+)
+
+// APIStatus is what the json api tells about a walk
+type APIStatus struct {
+	vo.ServiceStatus
+	Paused             bool
+	Halted             bool
+	Errors             int
+	ScrapeSpeed        float64
+	ScrapeSpeedAverage float64
+	ScrapeSpeedLimit   float64
+	// at least one loop has been completed
+	LoopComplete bool
+	// scheduled start of the next loop
+	NextRun time.Time
+	// name of the target
+	Target string
+}
+
+// APIResults is a page of filtered results
+type APIResults struct {
+	FilterOptions FilterOptions
+	Results       []vo.ScrapeResult
+	Page          int
+	PageSize      int
+	NumPages      int
+}
+
+// APIResult is a single result with the pages linking to it
+type APIResult struct {
+	Result  vo.ScrapeResult
+	Inbound []string
+}
+
+type apiError struct {
+	Error string
+}
+
+func apiReply(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "	")
+	errEncode := encoder.Encode(v)
+	if errEncode != nil {
+		fmt.Println("could not encode api response", errEncode)
+	}
+}
+
+func apiReplyError(w http.ResponseWriter, code int, err error) {
+	apiReply(w, code, apiError{Error: err.Error()})
+}
+
+func (s *Service) getAPIStatus() APIStatus {
+	walkerStatus := s.Walker.GetStatus()
+	apiStatus := APIStatus{
+		Paused:             walkerStatus.Paused,
+		Halted:             walkerStatus.Halted,
+		Errors:             walkerStatus.ErrorCount(),
+		ScrapeSpeed:        walkerStatus.ScrapeSpeed,
+		ScrapeSpeedAverage: walkerStatus.ScrapeSpeedAverage,
+		ScrapeSpeedLimit:   walkerStatus.ScrapeSpeedLimit,
+		LoopComplete:       s.Walker.GetCompleteStatus() != nil,
+		NextRun:            walkerStatus.NextRun,
+	}
+	apiStatus.Done = len(walkerStatus.Results)
+	for _, active := range walkerStatus.Jobs {
+		if active {
+			apiStatus.Pending++
+		} else {
+			apiStatus.Open++
+		}
+	}
+	if conf := s.GetConfig(); conf != nil {
+		apiStatus.TargetURL = conf.Target.BaseURL
+		apiStatus.Target = conf.Name
+	}
+	return apiStatus
+}
+
+func getAPIFilters(r *http.Request) (filters Filters, err error) {
+	query := r.URL.Query()
+	filters.Prefix = query.Get("prefix")
+	if status := query.Get("status"); status != "" {
+		for _, code := range strings.Split(status, ",") {
+			statusCode, errAtoi := strconv.Atoi(strings.TrimSpace(code))
+			if errAtoi != nil {
+				return filters, errAtoi
+			}
+			filters.Status = append(filters.Status, statusCode)
+		}
+	}
+	if minDur := query.Get("minDur"); minDur != "" {
+		filters.MinDur, err = time.ParseDuration(minDur)
+		if err != nil {
+			return filters, err
+		}
+	}
+	if maxDur := query.Get("maxDur"); maxDur != "" {
+		filters.MaxDur, err = time.ParseDuration(maxDur)
+		if err != nil {
+			return filters, err
+		}
+	}
+	return filters, nil
+}
+
+func queryInt(r *http.Request, name string, defaultValue int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// getResult looks into the running status first and falls back to the last complete loop
+func (s *Service) getResult(targetURL string) (result APIResult, ok bool) {
+	status := s.Walker.GetStatus()
+	r, ok := status.Results[targetURL]
+	if completeStatus := s.Walker.GetCompleteStatus(); !ok && completeStatus != nil {
+		status = *completeStatus
+		r, ok = status.Results[targetURL]
+	}
+	if !ok {
+		return result, false
+	}
+	result.Result = r
+	result.Inbound = []string{}
+	for _, other := range status.Results {
+		if _, linked := other.NormalizedLinks[targetURL]; linked {
+			result.Inbound = append(result.Inbound, other.TargetURL)
+		}
+	}
+	sort.Strings(result.Inbound)
+	return result, true
+}
+
+// authorizeControl tells, if a request may change the walk, that needs the apitoken of the config as a bearer token
+func (s *Service) authorizeControl(r *http.Request) (code int, err error) {
+	token := ""
+	if conf := s.GetConfig(); conf != nil {
+		token = conf.APIToken
+	}
+	if token == "" {
+		return http.StatusForbidden, errors.New("control is disabled, it needs an apitoken in the config")
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+		return http.StatusUnauthorized, errors.New("invalid api token")
+	}
+	return http.StatusOK, nil
+}
+
+// GetAPIHandler serves a json api for status, results and crawl control under basePath like /api/v1
+func (s *Service) GetAPIHandler(basePath string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, basePath)
+		route := r.Method + " " + path
+		switch route {
+		case "PUT /config", "POST /config", "POST /start", "POST /stop", "POST /pause", "POST /continue":
+			if code, errAuthorize := s.authorizeControl(r); errAuthorize != nil {
+				apiReplyError(w, code, errAuthorize)
+				return
+			}
+		}
+		switch route {
+		case "GET /status":
+			apiReply(w, http.StatusOK, s.getAPIStatus())
+		case "GET /results":
+			filters, errFilters := getAPIFilters(r)
+			if errFilters != nil {
+				apiReplyError(w, http.StatusBadRequest, errFilters)
+				return
+			}
+			page, errPage := queryInt(r, "page", 0)
+			if errPage != nil {
+				apiReplyError(w, http.StatusBadRequest, errPage)
+				return
+			}
+			pageSize, errPageSize := queryInt(r, "pageSize", 100)
+			if errPageSize != nil {
+				apiReplyError(w, http.StatusBadRequest, errPageSize)
+				return
+			}
+			filterOptions, results, numPages := s.GetResults(filters, page, pageSize)
+			apiReply(w, http.StatusOK, APIResults{
+				FilterOptions: filterOptions,
+				Results:       results,
+				Page:          page,
+				PageSize:      pageSize,
+				NumPages:      numPages,
+			})
+		case "GET /result":
+			result, ok := s.getResult(r.URL.Query().Get("url"))
+			if !ok {
+				apiReplyError(w, http.StatusNotFound, errors.New("no result for url"))
+				return
+			}
+			apiReply(w, http.StatusOK, result)
+		case "GET /history", "GET /history/trend":
+			history := s.Walker.GetHistory()
+			if history == nil {
+				apiReplyError(w, http.StatusNotFound, errors.New("the history is not enabled"))
+				return
+			}
+			last, errLast := queryInt(r, "last", 0)
+			if errLast != nil {
+				apiReplyError(w, http.StatusBadRequest, errLast)
+				return
+			}
+			if path == "/history" {
+				stats, errStats := history.Stats(last)
+				if errStats != nil {
+					apiReplyError(w, http.StatusInternalServerError, errStats)
+					return
+				}
+				apiReply(w, http.StatusOK, stats)
+				return
+			}
+			code, errCode := queryInt(r, "code", 0)
+			if errCode != nil {
+				apiReplyError(w, http.StatusBadRequest, errCode)
+				return
+			}
+			points, errTrend := history.Trend(TrendQuery{
+				Metric: r.URL.Query().Get("metric"),
+				Code:   code,
+				Group:  r.URL.Query().Get("group"),
+				URL:    r.URL.Query().Get("url"),
+				Last:   last,
+			})
+			if errTrend != nil {
+				apiReplyError(w, http.StatusBadRequest, errTrend)
+				return
+			}
+			apiReply(w, http.StatusOK, points)
+		case "GET /config":
+			apiReply(w, http.StatusOK, s.GetConfig())
+		case "PUT /config", "POST /config":
+			// yaml is a superset of json, so we take both
+			confBytes, errRead := ioutil.ReadAll(r.Body)
+			if errRead != nil {
+				apiReplyError(w, http.StatusBadRequest, errRead)
+				return
+			}
+			conf, errConf := config.Load(confBytes)
+			if errConf != nil {
+				apiReplyError(w, http.StatusBadRequest, errConf)
+				return
+			}
+			errReconfigure := s.Reconfigure(conf)
+			if errReconfigure != nil {
+				apiReplyError(w, http.StatusUnprocessableEntity, errReconfigure)
+				return
+			}
+			apiReply(w, http.StatusOK, s.getAPIStatus())
+		case "POST /start", "POST /stop", "POST /pause", "POST /continue":
+			var errControl error
+			switch path {
+			case "/start":
+				errControl = s.Restart()
+			case "/stop":
+				errControl = s.Walker.Halt()
+			case "/pause":
+				errControl = s.Walker.Pause()
+			case "/continue":
+				errControl = s.Walker.Continue()
+			}
+			if errControl != nil {
+				apiReplyError(w, http.StatusConflict, errControl)
+				return
+			}
+			apiReply(w, http.StatusOK, s.getAPIStatus())
+		default:
+			apiReplyError(w, http.StatusNotFound, errors.New("unknown route: "+route))
+		}
+	}
+}
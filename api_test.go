@@ -0,0 +1,79 @@
+package walker
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/foomo/walker/config"
+	"github.com/foomo/walker/vo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIHandler(t *testing.T) {
+	// a walker, that is done, serves its complete status
+	w := &Walker{chanDone: make(chan struct{})}
+	close(w.chanDone)
+	w.CompleteStatus = &vo.Status{
+		Results: map[string]vo.ScrapeResult{
+			"http://example.com/":    {TargetURL: "http://example.com/", Code: 200, NormalizedLinks: vo.LinkList{"http://example.com/a": 1}},
+			"http://example.com/a":   {TargetURL: "http://example.com/a", Code: 200},
+			"http://example.com/404": {TargetURL: "http://example.com/404", Code: 404},
+		},
+	}
+	s := &Service{Walker: w}
+	handler := s.GetAPIHandler("/api/v1")
+	get := func(method, path string, v interface{}) int {
+		recorder := httptest.NewRecorder()
+		handler(recorder, httptest.NewRequest(method, path, nil))
+		if v != nil {
+			assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), v))
+		}
+		return recorder.Code
+	}
+
+	apiStatus := APIStatus{}
+	assert.Equal(t, http.StatusOK, get(http.MethodGet, "/api/v1/status", &apiStatus))
+	assert.Equal(t, 3, apiStatus.Done)
+	assert.Equal(t, 1, apiStatus.Errors)
+
+	apiResults := APIResults{}
+	assert.Equal(t, http.StatusOK, get(http.MethodGet, "/api/v1/results?status=200&pageSize=1&page=1", &apiResults))
+	assert.Equal(t, 2, apiResults.NumPages)
+	assert.Len(t, apiResults.Results, 1)
+	assert.Equal(t, "http://example.com/a", apiResults.Results[0].TargetURL)
+	assert.Equal(t, http.StatusBadRequest, get(http.MethodGet, "/api/v1/results?status=foo", nil))
+
+	apiResult := APIResult{}
+	assert.Equal(t, http.StatusOK, get(http.MethodGet, "/api/v1/result?url=http://example.com/a", &apiResult))
+	assert.Equal(t, []string{"http://example.com/"}, apiResult.Inbound)
+	assert.Equal(t, http.StatusNotFound, get(http.MethodGet, "/api/v1/result?url=http://example.com/nope", nil))
+
+	assert.Equal(t, http.StatusNotFound, get(http.MethodGet, "/api/v1/pause", nil))
+
+	// control is disabled without an api token
+	control := func(path, token string) int {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader("target: http://example.com"))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		handler(recorder, req)
+		return recorder.Code
+	}
+	assert.Equal(t, http.StatusForbidden, control("/api/v1/pause", ""))
+	assert.Equal(t, http.StatusForbidden, control("/api/v1/config", ""))
+	s.conf = &config.Config{APIToken: "s3cret"}
+	for _, path := range []string{"/api/v1/config", "/api/v1/start", "/api/v1/stop", "/api/v1/pause", "/api/v1/continue"} {
+		assert.Equal(t, http.StatusUnauthorized, control(path, ""), path)
+		assert.Equal(t, http.StatusUnauthorized, control(path, "wrong"), path)
+	}
+	// the walker is done
+	assert.Equal(t, http.StatusConflict, control("/api/v1/pause", "s3cret"))
+	recorder := httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/config", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.NotContains(t, recorder.Body.String(), "s3cret")
+}
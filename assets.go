@@ -0,0 +1,118 @@
+package walker
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/foomo/walker/config"
+	"github.com/foomo/walker/vo"
+)
+
+func assetCheckerOptions(conf config.Assets) linkCheckerOptions {
+	return linkCheckerOptions{
+		concurrency:       conf.Concurrency,
+		requestsPerSecond: conf.RequestsPerSecond,
+		timeout:           conf.Timeout,
+		maxAge:            conf.MaxAge,
+		ignore:            conf.Ignore,
+		// we want to know how big they are
+		maxBody: 1024 * 1024 * 100,
+		measure: true,
+	}
+}
+
+// assetMaxSizes resolves the size limits for all asset types, types without a limit are omitted
+func assetMaxSizes(conf config.Assets) map[vo.AssetType]int64 {
+	maxSizes := map[vo.AssetType]int64{}
+	for _, assetType := range vo.AssetTypes {
+		maxSize := conf.MaxSize
+		if typeMaxSize, ok := conf.MaxSizeByType[string(assetType)]; ok {
+			maxSize = typeMaxSize
+		}
+		if maxSize > 0 {
+			maxSizes[assetType] = maxSize
+		}
+	}
+	return maxSizes
+}
+
+func hasRel(sel *goquery.Selection, rel string) bool {
+	attrRel, _ := sel.Attr("rel")
+	for _, r := range strings.Fields(strings.ToLower(attrRel)) {
+		if r == rel {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSrcset returns the urls from a srcset attribute like "a.jpg 1x, b.jpg 2x"
+func parseSrcset(srcset string) (urls []string) {
+	for _, candidate := range strings.Split(srcset, ",") {
+		fields := strings.Fields(candidate)
+		if len(fields) > 0 {
+			urls = append(urls, fields[0])
+		}
+	}
+	return urls
+}
+
+// extractAssets finds images, scripts, stylesheets, fonts and preloads in a document
+func extractAssets(doc *goquery.Document, baseURL *url.URL) vo.Assets {
+	assets := vo.Assets{}
+	add := func(assetType vo.AssetType, ref string) {
+		ref = strings.TrimSpace(ref)
+		if ref == "" {
+			return
+		}
+		refU, errNormalize := NormalizeLink(baseURL, ref)
+		if errNormalize != nil || (refU.Scheme != "http" && refU.Scheme != "https") {
+			// data:, blob: and friends
+			return
+		}
+		assets[refU.String()] = assetType
+	}
+	doc.Find("img").Each(func(i int, sel *goquery.Selection) {
+		src, _ := sel.Attr("src")
+		add(vo.AssetTypeImage, src)
+		srcset, _ := sel.Attr("srcset")
+		for _, src := range parseSrcset(srcset) {
+			add(vo.AssetTypeImage, src)
+		}
+	})
+	doc.Find("picture source[srcset]").Each(func(i int, sel *goquery.Selection) {
+		srcset, _ := sel.Attr("srcset")
+		for _, src := range parseSrcset(srcset) {
+			add(vo.AssetTypeImage, src)
+		}
+	})
+	doc.Find("script[src]").Each(func(i int, sel *goquery.Selection) {
+		src, _ := sel.Attr("src")
+		add(vo.AssetTypeScript, src)
+	})
+	doc.Find("link[href]").Each(func(i int, sel *goquery.Selection) {
+		href, _ := sel.Attr("href")
+		switch true {
+		case hasRel(sel, "stylesheet"):
+			add(vo.AssetTypeStylesheet, href)
+		case hasRel(sel, "icon"):
+			add(vo.AssetTypeImage, href)
+		case hasRel(sel, "preload") || hasRel(sel, "modulepreload"):
+			as, _ := sel.Attr("as")
+			switch strings.ToLower(as) {
+			case "font":
+				add(vo.AssetTypeFont, href)
+			case "style":
+				add(vo.AssetTypeStylesheet, href)
+			case "script":
+				add(vo.AssetTypeScript, href)
+			case "image":
+				add(vo.AssetTypeImage, href)
+			default:
+				add(vo.AssetTypePreload, href)
+			}
+		}
+	})
+	return assets
+}
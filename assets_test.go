@@ -0,0 +1,54 @@
+package walker
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/foomo/walker/config"
+	"github.com/foomo/walker/vo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractAssets(t *testing.T) {
+	doc, errDoc := goquery.NewDocumentFromReader(strings.NewReader(`<html><head>
+		<link rel="stylesheet" href="/css/main.css">
+		<link rel="shortcut icon" href="/favicon.ico">
+		<link rel="preload" as="font" href="/fonts/font.woff2">
+		<link rel="preload" href="/data.json">
+		<link rel="canonical" href="/page">
+		<script src="https://cdn.example.org/lib.js"></script>
+		<script>console.log("inline")</script>
+	</head><body>
+		<img src="/img/a.jpg" srcset="/img/a-1x.jpg 1x, /img/a-2x.jpg 2x">
+		<img src="data:image/gif;base64,R0lGODlhAQABAAAAACw=">
+		<picture><source srcset="/img/b.webp 480w, /img/b-large.webp 1024w"></picture>
+	</body></html>`))
+	assert.NoError(t, errDoc)
+	baseURL, _ := url.Parse("https://www.example.com/page")
+	assert.Equal(t, vo.Assets{
+		"https://www.example.com/css/main.css":     vo.AssetTypeStylesheet,
+		"https://www.example.com/favicon.ico":      vo.AssetTypeImage,
+		"https://www.example.com/fonts/font.woff2": vo.AssetTypeFont,
+		"https://www.example.com/data.json":        vo.AssetTypePreload,
+		"https://cdn.example.org/lib.js":           vo.AssetTypeScript,
+		"https://www.example.com/img/a.jpg":        vo.AssetTypeImage,
+		"https://www.example.com/img/a-1x.jpg":     vo.AssetTypeImage,
+		"https://www.example.com/img/a-2x.jpg":     vo.AssetTypeImage,
+		"https://www.example.com/img/b.webp":       vo.AssetTypeImage,
+		"https://www.example.com/img/b-large.webp": vo.AssetTypeImage,
+	}, extractAssets(doc, baseURL))
+}
+
+func TestAssetMaxSizes(t *testing.T) {
+	assert.Equal(t, map[vo.AssetType]int64{
+		vo.AssetTypeImage:      10,
+		vo.AssetTypeScript:     100,
+		vo.AssetTypeStylesheet: 100,
+		vo.AssetTypePreload:    100,
+	}, assetMaxSizes(config.Assets{
+		MaxSize:       100,
+		MaxSizeByType: map[string]int64{"image": 10, "font": 0},
+	}))
+}
@@ -0,0 +1,217 @@
+package walker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/foomo/walker/config"
+)
+
+// authenticator adds credentials to requests for the target and logs pool clients in
+type authenticator struct {
+	conf     config.Auth
+	host     string
+	loginURL *url.URL
+}
+
+func newAuthenticator(conf config.Auth, baseURL string) (a *authenticator, err error) {
+	if conf.Empty() {
+		return nil, nil
+	}
+	if conf.Username != "" && conf.BearerToken != "" {
+		return nil, errors.New("basic auth and a bearer token both need the authorization header")
+	}
+	baseU, errParse := url.Parse(baseURL)
+	if errParse != nil {
+		return nil, errParse
+	}
+	a = &authenticator{
+		conf: conf,
+		host: baseU.Host,
+	}
+	if !conf.Login.Empty() {
+		loginU, errLoginURL := NormalizeLink(baseU, conf.Login.URL)
+		if errLoginURL != nil {
+			return nil, errors.New("invalid login url: " + errLoginURL.Error())
+		}
+		if conf.Login.UsernameField == "" || conf.Login.PasswordField == "" {
+			return nil, errors.New("login needs a usernamefield and a passwordfield")
+		}
+		a.loginURL = loginU
+	}
+	return a, nil
+}
+
+func (a *authenticator) equals(other *authenticator) bool {
+	if a == nil || other == nil {
+		return a == other
+	}
+	return a.host == other.host && reflect.DeepEqual(a.conf, other.conf)
+}
+
+// authorize a request, if it goes to the target
+func (a *authenticator) authorize(req *http.Request) {
+	if req.URL.Host != a.host {
+		return
+	}
+	if a.conf.Username != "" {
+		req.SetBasicAuth(a.conf.Username, a.conf.Password)
+	}
+	if a.conf.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+a.conf.BearerToken)
+	}
+	for name, value := range a.conf.Headers {
+		req.Header.Set(name, value)
+	}
+}
+
+// strip the credentials from a request, that was redirected away from the target
+func (a *authenticator) strip(req *http.Request) {
+	if a == nil || req.URL.Host == a.host {
+		return
+	}
+	req.Header.Del("Authorization")
+	for name := range a.conf.Headers {
+		req.Header.Del(name)
+	}
+}
+
+// expired tells, if a response shows, that the session of a pool client is gone
+func (a *authenticator) expired(resp *http.Response) bool {
+	if a.loginURL == nil {
+		return false
+	}
+	for _, code := range a.conf.Login.ExpiredStatusCodes {
+		if resp.StatusCode == code {
+			return true
+		}
+	}
+	// redirected to the login page
+	return resp.Request != nil &&
+		len(getRedirectsFromRequest(resp.Request)) > 0 &&
+		resp.Request.URL.Host == a.loginURL.Host &&
+		resp.Request.URL.Path == a.loginURL.Path
+}
+
+// loginPool logs in all clients of a pool, every one of them has a cookie jar of its own
+func (a *authenticator) loginPool(ctx context.Context, cp *clientPool) error {
+	if a.loginURL == nil {
+		return nil
+	}
+	for _, pc := range cp.clients {
+		errLogin := a.login(ctx, pc)
+		if errLogin != nil {
+			return errors.New("login failed: " + errLogin.Error())
+		}
+	}
+	return nil
+}
+
+// login a pool client with the login form
+func (a *authenticator) login(ctx context.Context, pc *poolClient) error {
+	if pc.client.Jar == nil {
+		return errors.New("a login needs cookies")
+	}
+	conf := a.conf.Login
+	req, errRequest := pc.newRequest(http.MethodGet, a.loginURL.String())
+	if errRequest != nil {
+		return errRequest
+	}
+	resp, errGet := pc.client.Do(req.WithContext(ctx))
+	if errGet != nil {
+		return errGet
+	}
+	bodyBytes, errRead := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if errRead != nil {
+		return errRead
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("unexpected status of the login page: " + resp.Status)
+	}
+	doc, errDoc := goquery.NewDocumentFromReader(bytes.NewBuffer(bodyBytes))
+	if errDoc != nil {
+		return errDoc
+	}
+	var form *goquery.Selection
+	if conf.Form != "" {
+		form = doc.Find(conf.Form).First()
+	} else {
+		form = doc.Find("form").FilterFunction(func(i int, s *goquery.Selection) bool {
+			return s.Find("input[type=password]").Length() > 0
+		}).First()
+	}
+	if form.Length() == 0 {
+		return errors.New("no login form found on " + a.loginURL.String())
+	}
+	values := url.Values{}
+	form.Find("input[type=hidden]").Each(func(i int, s *goquery.Selection) {
+		if name, ok := s.Attr("name"); ok && name != "" {
+			values.Set(name, s.AttrOr("value", ""))
+		}
+	})
+	if conf.CSRFField != "" && values.Get(conf.CSRFField) == "" {
+		return errors.New("no csrf token " + conf.CSRFField + " in the login form")
+	}
+	for name, value := range conf.Fields {
+		values.Set(name, value)
+	}
+	values.Set(conf.UsernameField, conf.Username)
+	values.Set(conf.PasswordField, conf.Password)
+
+	// the page we ended up on, after following redirects
+	pageURL := resp.Request.URL
+	action := conf.Action
+	if action == "" {
+		action = form.AttrOr("action", "")
+	}
+	actionURL, errAction := pageURL.Parse(action)
+	if errAction != nil {
+		return errAction
+	}
+	method := strings.ToUpper(form.AttrOr("method", http.MethodPost))
+	var body io.Reader
+	if method == http.MethodGet {
+		actionURL.RawQuery = values.Encode()
+	} else {
+		method = http.MethodPost
+		body = strings.NewReader(values.Encode())
+	}
+	postReq, errPostRequest := pc.newRequestWithBody(method, actionURL.String(), body)
+	if errPostRequest != nil {
+		return errPostRequest
+	}
+	if body != nil {
+		postReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	postReq.Header.Set("Referer", pageURL.String())
+	postResp, errPost := pc.client.Do(postReq.WithContext(ctx))
+	if errPost != nil {
+		return errPost
+	}
+	io.Copy(ioutil.Discard, postResp.Body)
+	postResp.Body.Close()
+	if postResp.StatusCode >= 400 {
+		return errors.New("login was answered with " + postResp.Status)
+	}
+	if conf.SessionCookie != "" {
+		for _, cookie := range pc.client.Jar.Cookies(a.loginURL) {
+			if cookie.Name == conf.SessionCookie {
+				return nil
+			}
+		}
+		return errors.New("no session cookie " + conf.SessionCookie + " after login")
+	}
+	if postResp.Request.URL.Path == a.loginURL.Path && postResp.Request.URL.Host == a.loginURL.Host {
+		return errors.New("still on the login page after login")
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package walker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/foomo/walker/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthorize(t *testing.T) {
+	auth, errAuth := newAuthenticator(config.Auth{
+		Username: "stage",
+		Password: "secret",
+		Headers:  map[string]string{"X-Api-Key": "key"},
+	}, "https://stage.example.com/")
+	require.NoError(t, errAuth)
+
+	req, _ := http.NewRequest(http.MethodGet, "https://stage.example.com/page", nil)
+	auth.authorize(req)
+	username, password, ok := req.BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "stage", username)
+	assert.Equal(t, "secret", password)
+	assert.Equal(t, "key", req.Header.Get("X-Api-Key"))
+
+	// no credentials for other hosts
+	req, _ = http.NewRequest(http.MethodGet, "https://cdn.example.com/image.png", nil)
+	auth.authorize(req)
+	assert.Empty(t, req.Header.Get("Authorization"))
+	assert.Empty(t, req.Header.Get("X-Api-Key"))
+
+	bearerAuth, errBearerAuth := newAuthenticator(config.Auth{BearerToken: "token"}, "https://stage.example.com/")
+	require.NoError(t, errBearerAuth)
+	req, _ = http.NewRequest(http.MethodGet, "https://stage.example.com/page", nil)
+	bearerAuth.authorize(req)
+	assert.Equal(t, "Bearer token", req.Header.Get("Authorization"))
+
+	_, errBoth := newAuthenticator(config.Auth{Username: "stage", BearerToken: "token"}, "https://stage.example.com/")
+	assert.Error(t, errBoth)
+
+	noAuth, errNoAuth := newAuthenticator(config.Auth{}, "https://stage.example.com/")
+	assert.NoError(t, errNoAuth)
+	assert.Nil(t, noAuth)
+}
+
+func TestAuthorizeRedirect(t *testing.T) {
+	otherHeaders := http.Header{}
+	otherServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		otherHeaders = r.Header.Clone()
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><title>other</title></html>"))
+	}))
+	defer otherServer.Close()
+	targetHeaders := http.Header{}
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		targetHeaders = r.Header.Clone()
+		http.Redirect(w, r, otherServer.URL+"/", http.StatusFound)
+	}))
+	defer testServer.Close()
+	auth, errAuth := newAuthenticator(config.Auth{
+		BearerToken: "token",
+		Headers:     map[string]string{"X-Api-Key": "key"},
+	}, testServer.URL)
+	require.NoError(t, errAuth)
+	baseURL, _ := url.Parse(testServer.URL)
+	cp := newClientPool(1, "test", false, testHTTPClientSettings(config.HTTP{}), auth)
+	chanResult := make(chan scrapeResultAndClient, 1)
+	scrape(context.Background(), cp.clients[0], testServer.URL+"/", baseURL, "", nil, nil, nil, retryPolicy(config.Retry{MaxAttempts: 1}), nil, chanResult)
+	result := (<-chanResult).result
+	assert.Equal(t, http.StatusOK, result.Code)
+	assert.Equal(t, "key", targetHeaders.Get("X-Api-Key"))
+	assert.Equal(t, "Bearer token", targetHeaders.Get("Authorization"))
+	// the credentials of the target stay with the target
+	assert.Empty(t, otherHeaders.Get("X-Api-Key"))
+	assert.Empty(t, otherHeaders.Get("Authorization"))
+	assert.Equal(t, "test", otherHeaders.Get("User-Agent"))
+}
+
+func TestFormLogin(t *testing.T) {
+	mutex := sync.Mutex{}
+	sessions := map[string]bool{}
+	logins := 0
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		switch r.URL.Path {
+		case "/login":
+			if r.Method == http.MethodGet {
+				http.SetCookie(w, &http.Cookie{Name: "csrf", Value: "csrf-token", Path: "/"})
+				w.Header().Set("Content-Type", "text/html")
+				w.Write([]byte(`<html><body>
+					<form action="/search"><input name="q"></form>
+					<form method="post" action="/login">
+						<input type="hidden" name="_csrf" value="csrf-token">
+						<input name="email"><input type="password" name="password">
+					</form>
+				</body></html>`))
+				return
+			}
+			csrfCookie, errCookie := r.Cookie("csrf")
+			if errCookie != nil || r.PostFormValue("_csrf") != csrfCookie.Value ||
+				r.PostFormValue("email") != "shopper@example.com" || r.PostFormValue("password") != "secret" {
+				http.Redirect(w, r, "/login", http.StatusSeeOther)
+				return
+			}
+			logins++
+			session := "session-" + strconv.Itoa(logins)
+			sessions[session] = true
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: session, Path: "/"})
+			http.Redirect(w, r, "/account", http.StatusSeeOther)
+		default:
+			sessionCookie, errCookie := r.Cookie("session")
+			if errCookie != nil || !sessions[sessionCookie.Value] {
+				http.Redirect(w, r, "/login", http.StatusFound)
+				return
+			}
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><title>account</title></html>"))
+		}
+	}))
+	defer testServer.Close()
+	baseURL, _ := url.Parse(testServer.URL)
+
+	auth, errAuth := newAuthenticator(config.Auth{
+		Login: config.Login{
+			URL:                "/login",
+			UsernameField:      "email",
+			PasswordField:      "password",
+			Username:           "shopper@example.com",
+			Password:           "secret",
+			CSRFField:          "_csrf",
+			SessionCookie:      "session",
+			ExpiredStatusCodes: []int{http.StatusUnauthorized},
+		},
+	}, testServer.URL)
+	require.NoError(t, errAuth)
+	cp := newClientPool(2, "test", true, testHTTPClientSettings(config.HTTP{}), auth)
+	require.NoError(t, auth.loginPool(context.Background(), cp))
+	assert.Equal(t, 2, logins)
+
+	chanResult := make(chan scrapeResultAndClient, 1)
+	scrape(context.Background(), cp.clients[0], testServer.URL+"/account", baseURL, "", nil, nil, nil, retryPolicy(config.Retry{MaxAttempts: 1}), nil, chanResult)
+	result := (<-chanResult).result
+	assert.Equal(t, "account", result.Structure.Title)
+	assert.Empty(t, result.Attempts)
+
+	// the session expires and the client logs in again
+	mutex.Lock()
+	sessions = map[string]bool{}
+	mutex.Unlock()
+	scrape(context.Background(), cp.clients[0], testServer.URL+"/account", baseURL, "", nil, nil, nil, retryPolicy(config.Retry{MaxAttempts: 1}), nil, chanResult)
+	result = (<-chanResult).result
+	assert.Equal(t, "account", result.Structure.Title)
+	require.Len(t, result.Attempts, 1)
+	assert.Equal(t, "session expired", result.Attempts[0].Status)
+	assert.Equal(t, 3, logins)
+
+	// wrong credentials
+	wrongAuth, _ := newAuthenticator(config.Auth{
+		Login: config.Login{
+			URL:           "/login",
+			UsernameField: "email",
+			PasswordField: "password",
+			Username:      "shopper@example.com",
+			Password:      "wrong",
+		},
+	}, testServer.URL)
+	wrongCP := newClientPool(1, "test", true, testHTTPClientSettings(config.HTTP{}), wrongAuth)
+	assert.Error(t, wrongAuth.loginPool(context.Background(), wrongCP))
+}
@@ -1,34 +1,49 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"html"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
 
 	"github.com/foomo/walker"
 	"github.com/foomo/walker/config"
 	"github.com/foomo/walker/reports"
 	"github.com/foomo/walker/vo"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	yaml "gopkg.in/yaml.v3"
 )
 
 type server struct {
-	s             *walker.Service
-	conf          string
-	reportHandler func(
-		w http.ResponseWriter, r *http.Request,
-		completeStatus, runningStatus *vo.Status,
-	)
+	targets        *walker.Targets
+	apiHandler     http.HandlerFunc
 	metricsHandler http.HandlerFunc
 }
 
-const pathReports = "/reports"
+const (
+	pathReports = "/reports"
+	pathAPI     = "/api/v1"
+)
 
-func htmlIndex() []byte {
+func (s *server) htmlIndex() []byte {
+	targets := ""
+	menu := reports.GetReportHandlerMenuHTML(pathReports)
+	if len(s.targets.Names) > 1 {
+		menu = ""
+		for _, name := range s.targets.Names {
+			targets += `<li><a href="/status?target=` + url.QueryEscape(name) + `">` + html.EscapeString(name) + `</a></li>`
+			menu += `<h2>` + html.EscapeString(name) + `</h2>` + reports.GetReportHandlerMenuHTML(pathReports+"/"+url.PathEscape(name))
+		}
+		targets = `<p>targets</p><ul>` + targets + `</ul>`
+	}
 	return []byte(
 		`<html>
 			<head><title>Walker</title></head>
@@ -53,16 +68,17 @@ func htmlIndex() []byte {
 			<ul>
 				<li><a href="/status">crawling status</a></li>
 				<li><a href="/metrics">prometheus metrics scraping endpoint</a></li>
+				<li><a href="` + pathAPI + `/status">json api</a></li>
 			</ul>
-	
-			` + reports.GetReportHandlerMenuHTML(pathReports) + `
+			` + targets + `
+			` + menu + `
 			</body>
 		</html>`)
 }
 
 func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path == "/" {
-		w.Write(htmlIndex())
+		w.Write(s.htmlIndex())
 		return
 	}
 	if r.URL.Path == "/metrics" {
@@ -70,14 +86,40 @@ func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if r.URL.Path == "/status" {
+		service, ok := s.targets.Get(r.URL.Query().Get("target"))
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
 		w.Write([]byte(":::::::::::::::::: STATUS ::::::::::::::::::\n"))
-		w.Write([]byte("\nrunning with config:\n\n" + s.conf + "\n"))
-		s.s.Walker.PrintStatus(w, s.s.Walker.GetStatus())
+		yamlConfBytes, _ := yaml.Marshal(service.GetConfig().Redacted())
+		w.Write([]byte("\nrunning with config:\n\n" + string(yamlConfBytes) + "\n"))
+		service.Walker.PrintStatus(w, service.Walker.GetStatus())
+		return
+	}
+	if strings.HasPrefix(r.URL.Path, pathAPI+"/") {
+		s.apiHandler(w, r)
 		return
 	}
 	if strings.HasPrefix(r.URL.Path, pathReports) {
-		runningStatus := s.s.Walker.GetStatus()
-		s.reportHandler(w, r, s.s.Walker.CompleteStatus, &runningStatus)
+		// /reports/name/report or /reports/report?target=name
+		name := r.URL.Query().Get("target")
+		basePath := pathReports
+		for _, targetName := range s.targets.Names {
+			targetPath := pathReports + "/" + url.PathEscape(targetName)
+			if strings.HasPrefix(r.URL.Path, targetPath+"/") {
+				name = targetName
+				basePath = targetPath
+				break
+			}
+		}
+		service, ok := s.targets.Get(name)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		runningStatus := service.Walker.GetStatus()
+		reports.GetReportHandler(basePath)(w, r, service.Walker.GetCompleteStatus(), &runningStatus)
 		return
 	}
 	http.NotFound(w, r)
@@ -90,38 +132,164 @@ func must(comment string, err error) {
 	}
 }
 
+// targetFile inserts the target name before the extension, if there is more than one target
+func targetFile(filename, name string, numTargets int) string {
+	if filename == "" || numTargets < 2 {
+		return filename
+	}
+	ext := filepath.Ext(filename)
+	return strings.TrimSuffix(filename, ext) + "-" + name + ext
+}
+
+func writeReports(names []string, format reports.Format, out string, targetNames []string, statuses map[string]vo.Status) error {
+	reportList := []*reports.Report{}
+	for _, targetName := range targetNames {
+		for _, name := range names {
+			report, errBuild := reports.Build(strings.TrimSpace(name), statuses[targetName])
+			if errBuild != nil {
+				return errBuild
+			}
+			if len(targetNames) > 1 {
+				report.Name = targetName + "/" + report.Name
+			}
+			reportList = append(reportList, report)
+		}
+	}
+	if out == "" || out == "-" {
+		return reports.Render(os.Stdout, format, reportList)
+	}
+	f, errCreate := os.Create(out)
+	if errCreate != nil {
+		return errCreate
+	}
+	defer f.Close()
+	return reports.Render(f, format, reportList)
+}
+
+// serveStatus in the background of a single pass, the status server is a convenience,
+// a taken port must not end the run with the wrong exit code
+func serveStatus(addr string, handler http.Handler) (chanErr chan error) {
+	chanErr = make(chan error, 1)
+	go func() {
+		errServe := http.ListenAndServe(addr, handler)
+		fmt.Println("running without a status server on", addr, errServe)
+		chanErr <- errServe
+	}()
+	return chanErr
+}
+
 func main() {
+	flagOnce := flag.Bool("once", false, "walk the site once and exit with a non zero code, if errors were found")
+	flagFormat := flag.String("format", "", "render reports after a single pass walk: text, json, csv, junit or sarif")
+	flagReports := flag.String("reports", strings.Join(reports.DefaultNames, ","), "comma separated reports to render with -format")
+	flagOut := flag.String("out", "", "file to write the reports to, defaults to stdout")
+	flagBaseline := flag.String("baseline", "", "snapshot of a previous crawl to diff against")
+	flagSnapshot := flag.String("snapshot", "", "save a snapshot of the single pass walk to diff the next one against")
 	flag.Parse()
 	if len(flag.Args()) != 1 {
-		fmt.Println("usage:", os.Args[0], "path/to/config.yaml")
+		fmt.Println("usage:", os.Args[0], "[-once] [-format junit] [-reports errors,seo] [-out report.xml] path/to/config.yaml")
 		os.Exit(1)
 	}
+	format, errFormat := reports.ParseFormat(*flagFormat)
+	must("format error:", errFormat)
 	conf, errConf := config.Get(flag.Arg(0))
 	must("config error:", errConf)
+	if *flagOnce {
+		conf.Once = true
+	}
+	targetConfs := conf.TargetConfigs()
+	if *flagBaseline != "" {
+		for _, targetConf := range targetConfs {
+			targetConf.Baseline = targetFile(*flagBaseline, targetConf.Name, len(targetConfs))
+		}
+	}
 
-	yamlConfBytes, _ := yaml.Marshal(conf)
+	yamlConfBytes, _ := yaml.Marshal(conf.Redacted())
 	fmt.Println("this is how I understood your config:")
 	fmt.Println("------------------------------------------------------------------")
 	fmt.Println(string(yamlConfBytes))
 	fmt.Println("------------------------------------------------------------------")
 
-	s, chanLoopComplete, errS := walker.NewService(conf, nil, nil, nil, nil)
+	// terminate cleanly on ctrl+c
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
 
-	must("could not start service", errS)
+	targets, chanLoopComplete, errTargets := walker.NewTargets(ctx, conf, prometheus.DefaultRegisterer)
 
-	go func() {
-		for {
-			select {
-			case completeStatus := <-chanLoopComplete:
-				fmt.Println("a loop was completed, I walked around", len(completeStatus.Results), "docs")
+	must("could not start service", errTargets)
+
+	srv := &server{
+		targets:        targets,
+		apiHandler:     targets.GetAPIHandler(pathAPI),
+		metricsHandler: promhttp.Handler().ServeHTTP,
+	}
+
+	if conf.Once {
+		serveStatus(conf.Addr, srv)
+		// closed, when all walkers are done
+		completeStatuses := map[string]vo.Status{}
+		for targetStatus := range chanLoopComplete {
+			completeStatuses[targetStatus.Name] = targetStatus.Status
+		}
+		if len(completeStatuses) < len(targets.Names) {
+			fmt.Println("walker terminated without completing a loop")
+			os.Exit(1)
+		}
+		failed := false
+		for i, name := range targets.Names {
+			completeStatus := completeStatuses[name]
+			targetConf := targetConfs[i]
+			if *flagSnapshot != "" {
+				must("could not save snapshot:", walker.SaveSnapshot(targetFile(*flagSnapshot, name, len(targetConfs)), completeStatus))
+			}
+			if len(targetConfs) > 1 {
+				fmt.Println("target", name)
+			}
+			if *flagFormat == "" {
+				s, _ := targets.Get(name)
+				s.Walker.PrintStatus(os.Stdout, completeStatus)
+			}
+			if !targetConf.QualityGate.Empty() {
+				// the gate decides, how many errors are acceptable
+				gateResult, errGate := walker.EvaluateQualityGate(targetConf.QualityGate, completeStatus)
+				must("could not evaluate quality gate:", errGate)
+				walker.PrintQualityGate(os.Stdout, gateResult)
+				if !gateResult.Passed() {
+					failed = true
+				}
+				continue
 			}
+			errorCount := completeStatus.ErrorCount()
+			if errorCount > 0 {
+				fmt.Println("walked around", len(completeStatus.Results), "docs and found", errorCount, "errors")
+				failed = true
+				continue
+			}
+			fmt.Println("walked around", len(completeStatus.Results), "docs without errors")
+		}
+		if *flagFormat != "" {
+			must("could not write reports:", writeReports(strings.Split(*flagReports, ","), format, *flagOut, targets.Names, completeStatuses))
+		}
+		if failed {
+			os.Exit(2)
+		}
+		return
+	}
+
+	go func() {
+		for targetStatus := range chanLoopComplete {
+			fmt.Println("a loop of", targetStatus.Name, "was completed, I walked around", len(targetStatus.Status.Results), "docs")
 		}
 	}()
 
-	log.Fatal(http.ListenAndServe(conf.Addr, &server{
-		conf:           string(yamlConfBytes),
-		s:              s,
-		reportHandler:  reports.GetReportHandler(pathReports),
-		metricsHandler: promhttp.Handler().ServeHTTP,
-	}))
+	httpServer := &http.Server{Addr: conf.Addr, Handler: srv}
+	go func() {
+		<-ctx.Done()
+		httpServer.Shutdown(context.Background())
+	}()
+	errServe := httpServer.ListenAndServe()
+	if errServe != http.ErrServerClosed {
+		log.Fatal(errServe)
+	}
+	fmt.Println("walker terminated")
 }
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeStatusTakenPort(t *testing.T) {
+	listener, errListen := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, errListen)
+	defer listener.Close()
+	// the run goes on without a status server
+	chanErr := serveStatus(listener.Addr().String(), http.NotFoundHandler())
+	select {
+	case errServe := <-chanErr:
+		assert.Error(t, errServe)
+	case <-time.After(time.Second * 5):
+		t.Fatal("serving on a taken port did not fail")
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/url"
+	"path/filepath"
 	"strings"
+	"time"
 
 	yaml "gopkg.in/yaml.v3"
 )
@@ -16,13 +18,303 @@ type CheckContent struct {
 	Tags        []string
 }
 
+// Politeness limits how hard we hit a host
+type Politeness struct {
+	// maximum number of requests per second and host, 0 means no limit
+	RequestsPerSecond float64
+	// do not honor Crawl-delay from robots.txt
+	IgnoreCrawlDelay bool
+	// responses slower than this make us back off, 0 means never
+	SlowDuration time.Duration
+	// upper limit for the adaptive backoff
+	MaxBackoff time.Duration
+}
+
+// Retry policy for transient scrape failures
+type Retry struct {
+	// total number of attempts, 0 and 1 mean no retries
+	MaxAttempts int
+	// wait before the first retry, doubled for every following retry
+	Backoff time.Duration
+	// upper limit for waiting, also applies to Retry-After
+	MaxBackoff time.Duration
+	// status codes, that are worth another try
+	StatusCodes []int
+	// retry on timeouts, connection resets and the like
+	NetworkErrors bool
+}
+
+// Sitemap seeding of the frontier
+type Sitemap struct {
+	// add the urls from the sitemaps to the jobs
+	Seed bool
+	// sitemap urls or paths, default are the sitemaps from robots.txt or /sitemap.xml
+	URLs []string
+}
+
+// ExternalLinks validation of links to other hosts
+type ExternalLinks struct {
+	Check bool
+	// number of concurrent checks
+	Concurrency int
+	// maximum number of requests per second and external host
+	RequestsPerSecond float64
+	Timeout           time.Duration
+	// how long results are cached
+	MaxAge time.Duration
+	// url prefixes of links, that will not be checked
+	Ignore []string
+}
+
+// Assets checking of images, scripts, stylesheets and fonts embedded in pages
+type Assets struct {
+	Check             bool
+	Concurrency       int
+	RequestsPerSecond float64
+	Timeout           time.Duration
+	// how long results are cached
+	MaxAge time.Duration
+	// url prefixes of assets, that will not be checked
+	Ignore []string
+	// bytes, 0 means no limit
+	MaxSize int64
+	// bytes for asset types like image, script, stylesheet, font, preload
+	MaxSizeByType map[string]int64
+}
+
+// QualityGate thresholds, that are evaluated against a complete crawl, unset thresholds are not checked
+type QualityGate struct {
+	// maximum number of results with a 5xx status code
+	Max5xx *int
+	// maximum number of 404s plus dead external links
+	MaxBrokenLinks *int
+	// maximum share 0..1 of pages as slow or slower than a bucket from vo.GetBucketList, keys are bucket names or their lower boundaries like 500ms
+	MaxShareSlowerThan map[string]float64
+	// minimum average htmlschema score per group
+	MinSchemaScore map[string]int
+	// validation levels like error or warning, that must not occur
+	ForbiddenValidationLevels []string
+}
+
+// Empty tells, if no thresholds are set at all
+func (qg QualityGate) Empty() bool {
+	return qg.Max5xx == nil &&
+		qg.MaxBrokenLinks == nil &&
+		len(qg.MaxShareSlowerThan) == 0 &&
+		len(qg.MinSchemaScore) == 0 &&
+		len(qg.ForbiddenValidationLevels) == 0
+}
+
+// History of complete crawls
+type History struct {
+	// number of crawls to keep, 0 disables the history
+	Keep int
+	// defaults to history in StateDir
+	Dir string
+}
+
+// HTTP client settings for all requests, zero timeouts and limits fall back to DefaultHTTP
+type HTTP struct {
+	// skip the verification of certificates, problems still show up in the tls report
+	InsecureSkipVerify bool
+	// pem files with additional root certificates
+	CAFiles []string
+	// pem files of a client certificate and its key
+	ClientCertFile string
+	ClientKeyFile  string
+	// whole request including redirects and reading the body
+	Timeout               time.Duration
+	DialTimeout           time.Duration
+	KeepAlive             time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+	IdleConnTimeout       time.Duration
+	MaxIdleConns          int
+	MaxIdleConnsPerHost   int
+	// http(s) proxy url, without one HTTP_PROXY, HTTPS_PROXY and NO_PROXY are used
+	Proxy string
+	// extra headers for requests to the target like Accept-Language or a Cookie with feature flags
+	Headers map[string]string
+}
+
+// DefaultHTTP settings
+func DefaultHTTP() HTTP {
+	return HTTP{
+		Timeout:               time.Second * 10,
+		DialTimeout:           time.Second * 5,
+		KeepAlive:             time.Second * 5,
+		TLSHandshakeTimeout:   time.Second * 5,
+		ResponseHeaderTimeout: time.Second * 10,
+		IdleConnTimeout:       time.Second * 15,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   50,
+	}
+}
+
+// Budget limits a loop, zero values mean no limit
+type Budget struct {
+	// maximum number of clicks from the paths of the target, pages only found in sitemaps are not limited
+	MaxHops int
+	// maximum number of pages per loop
+	MaxPages int
+	// groups are guessed before fetching from the pages next to or above a page, full groups are not fetched, other pages beyond the budget of their group are dropped after fetching
+	MaxPagesPerGroup map[string]int
+	// maximum duration of a loop
+	MaxDuration time.Duration
+}
+
+// Priority of jobs, jobs with higher priorities are fetched first, equal priorities breadth first
+type Priority struct {
+	// depth first instead of breadth first for equal priorities
+	DepthFirst bool
+	// the first path with a matching regex adds its priority
+	Paths []PathPriority
+	// adds the priority of sitemap entries times this weight
+	SitemapWeight float64
+}
+
+// Frontier limits the memory of the priority queue of pending jobs, the urls, hops and discoveries of all jobs stay in memory
+type Frontier struct {
+	// jobs beyond this number wait in a spill file, 0 keeps all of them in memory
+	MaxJobs int
+	// directory of the spill file, defaults to the temp dir
+	SpillDir string
+}
+
+// PathPriority for paths matching a regular expression
+type PathPriority struct {
+	Regex    string
+	Priority float64
+}
+
+// Rule includes or excludes links, the first matching rule decides, all of its conditions have to match
+type Rule struct {
+	// include or exclude
+	Action string
+	// regular expression for the path like ^/p/[0-9]+$
+	Regex string
+	// glob for the path, * does not match /, ** does like /*/filter/**
+	Glob string
+	// query conditions like "page > 5", "sort = price", "q ~ ^[a-z]+$", "session" (exists) or "!page" (missing)
+	Query []string
+	// maximum number of links included by the rule, 0 means no limit
+	Limit int
+	// shows up in the explain report, defaults to the conditions
+	Name string
+}
+
+// Normalize urls, before they become jobs, so that different spellings of a page are crawled once,
+// schemes and hosts are always lower cased, default ports removed and percent encodings normalized
+type Normalize struct {
+	// "add" or "remove" trailing slashes, the root path and paths with a file extension are left alone
+	TrailingSlash string
+	// lower case paths for case insensitive servers
+	LowerCasePath bool
+	// sort query parameters by name
+	SortQuery bool
+	// query parameters to remove like tracking parameters, a trailing * matches prefixes like utm_*
+	StripQueryParameters []string
+	// index files like index.html, that are collapsed to their directory
+	IndexFiles []string
+}
+
+// Auth for the target, credentials are only sent to the host of the target and never show up in the config api
+type Auth struct {
+	// basic auth without putting it into the url of the target
+	Username string
+	Password string `json:"-"`
+	// sent as Authorization: Bearer <token>
+	BearerToken string `json:"-"`
+	// token headers like X-Api-Key
+	Headers map[string]string `json:"-"`
+	// form login, that populates the cookie jars before a loop starts
+	Login Login
+}
+
+// Empty tells, if there is nothing to authenticate with
+func (a Auth) Empty() bool {
+	return a.Username == "" && a.BearerToken == "" && len(a.Headers) == 0 && a.Login.Empty()
+}
+
+// redacted copy without secrets
+func (a Auth) redacted() Auth {
+	a.Password = redact(a.Password)
+	a.BearerToken = redact(a.BearerToken)
+	if a.Headers != nil {
+		headers := make(map[string]string, len(a.Headers))
+		for name, value := range a.Headers {
+			headers[name] = redact(value)
+		}
+		a.Headers = headers
+	}
+	a.Login.Password = redact(a.Login.Password)
+	if a.Login.Fields != nil {
+		// additional fields like otp codes or pins may be secret
+		fields := make(map[string]string, len(a.Login.Fields))
+		for name, value := range a.Login.Fields {
+			fields[name] = redact(value)
+		}
+		a.Login.Fields = fields
+	}
+	return a
+}
+
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "redacted"
+}
+
+// Login with a html form: the login page is loaded, all hidden inputs like csrf tokens are posted back with the credentials
+type Login struct {
+	// path or url of the login page
+	URL string
+	// css selector of the form, defaults to the first form with a password input
+	Form string
+	// defaults to the action of the form
+	Action        string
+	UsernameField string
+	PasswordField string
+	Username      string
+	Password      string `json:"-"`
+	// name of the input with the csrf token, if set, the login fails without one
+	CSRFField string
+	// additional fields to post
+	Fields map[string]string
+	// a cookie, that has to be set after a successful login
+	SessionCookie string
+	// a session expired, when a response has one of these status codes or ends up on the login page
+	ExpiredStatusCodes []int
+}
+
+// Empty tells, if there is no form login
+func (l Login) Empty() bool {
+	return l.URL == ""
+}
+
+// Schedule of the loops, without one the next loop starts right after the previous one
+type Schedule struct {
+	// minimum time between the starts of two loops
+	Interval time.Duration
+	// cron expression with minute, hour, day of month, month and day of week like "0 3 * * *"
+	Cron string
+}
+
+// Empty tells, if loops run back to back
+func (s Schedule) Empty() bool {
+	return s.Interval == 0 && s.Cron == ""
+}
+
 type Target struct {
 	BaseURL string
 	Paths   []string
 }
 type config struct {
+	Name              string
 	Concurrency       int
 	Addr              string
+	APIToken          string
 	Target            interface{}
 	Ignore            []string
 	IgnoreQueriesWith []string
@@ -34,6 +326,28 @@ type config struct {
 	GroupHeader       string
 	Agent             string
 	SchemaRoot        string
+	StateDir          string
+	Resume            bool
+	Baseline          string
+	Once              bool
+	Politeness        Politeness
+	Retry             Retry
+	Sitemap           Sitemap
+	ExternalLinks     ExternalLinks
+	Forbidden         []string
+	Assets            Assets
+	QualityGate       QualityGate
+	History           History
+	Schedule          Schedule
+	HTTP              HTTP
+	Auth              Auth
+	Normalize         Normalize
+	Rules             []Rule
+	ExplainLinks      bool
+	Budget            Budget
+	Priority          Priority
+	Frontier          Frontier
+	Targets           []yaml.Node
 }
 
 // type shortConfig struct {
@@ -41,8 +355,12 @@ type config struct {
 // }
 
 type Config struct {
-	Concurrency       int
-	Addr              string
+	// of the target, used for the walker label in metrics and to select a target
+	Name        string
+	Concurrency int
+	Addr        string
+	// bearer token for the control routes of the json api, they are disabled without one
+	APIToken          string `json:"-"`
 	Target            Target
 	Ignore            []string
 	IgnoreQueriesWith []string
@@ -54,6 +372,40 @@ type Config struct {
 	GroupHeader       string
 	Agent             string
 	SchemaRoot        string
+	StateDir          string
+	Resume            bool
+	Baseline          string
+	Once              bool
+	Politeness        Politeness
+	Retry             Retry
+	Sitemap           Sitemap
+	ExternalLinks     ExternalLinks
+	// hosts and urls, that must not be referenced, like stage systems
+	Forbidden   []string
+	Assets      Assets
+	QualityGate QualityGate
+	History     History
+	Schedule    Schedule
+	HTTP        HTTP
+	Auth        Auth
+	Normalize   Normalize
+	// ordered include and exclude rules, links without a matching rule are filtered by Ignore, Target.Paths and the query settings
+	Rules []Rule
+	// record, why links were followed or not for the explain report
+	ExplainLinks bool
+	Budget       Budget
+	Priority     Priority
+	Frontier     Frontier
+	// complete configs of all targets, empty if the config is a single target
+	Targets []*Config
+}
+
+// TargetConfigs returns the configs of all targets, a config without targets is its own and only target
+func (c *Config) TargetConfigs() []*Config {
+	if len(c.Targets) > 0 {
+		return c.Targets
+	}
+	return []*Config{c}
 }
 
 func Get(filename string) (conf *Config, err error) {
@@ -65,24 +417,134 @@ func Get(filename string) (conf *Config, err error) {
 	return Load(yamlBytes)
 }
 
-func Load(yamlBytes []byte) (conf *Config, err error) {
-	cnf := &config{
+func newDefaultConfig() *config {
+	return &config{
 		Concurrency:      2,
 		Addr:             ":3001",
 		UseCookies:       true,
 		IgnoreAllQueries: false,
 		IgnoreRobots:     false,
 		Agent:            "foomo-walker",
+		HTTP:             DefaultHTTP(),
+		Auth: Auth{
+			Login: Login{
+				UsernameField:      "username",
+				PasswordField:      "password",
+				ExpiredStatusCodes: []int{401},
+			},
+		},
+		Politeness: Politeness{
+			MaxBackoff: time.Second * 30,
+		},
+		Frontier: Frontier{
+			MaxJobs: 100000,
+		},
+		Retry: Retry{
+			MaxAttempts:   1,
+			Backoff:       time.Second,
+			MaxBackoff:    time.Second * 30,
+			StatusCodes:   []int{429, 502, 503, 504},
+			NetworkErrors: true,
+		},
+		ExternalLinks: ExternalLinks{
+			Concurrency:       2,
+			RequestsPerSecond: 1,
+			Timeout:           time.Second * 10,
+			MaxAge:            time.Hour * 24,
+		},
+		Assets: Assets{
+			Concurrency:       2,
+			RequestsPerSecond: 5,
+			Timeout:           time.Second * 30,
+			MaxAge:            time.Hour,
+		},
+	}
+}
+
+// Redacted copy of the config without credentials, use it to print a config
+func (c *Config) Redacted() *Config {
+	redacted := *c
+	redacted.APIToken = redact(c.APIToken)
+	redacted.Auth = c.Auth.redacted()
+	if c.Targets != nil {
+		redacted.Targets = make([]*Config, len(c.Targets))
+		for i, target := range c.Targets {
+			redacted.Targets[i] = target.Redacted()
+		}
 	}
+	return &redacted
+}
+
+// Load a config, every target in targets inherits everything from the top level and can override it
+func Load(yamlBytes []byte) (conf *Config, err error) {
+	cnf := newDefaultConfig()
 	errUnmarshal := yaml.Unmarshal(yamlBytes, &cnf)
 	if errUnmarshal != nil {
 		err = errUnmarshal
 		return
 	}
+	if len(cnf.Targets) == 0 {
+		return cnf.toConfig()
+	}
+	if cnf.Baseline != "" {
+		return nil, errors.New("a baseline has to be set per target")
+	}
+	// the top level target is only a default for the targets
+	conf = cnf.mapConfig()
+	names := map[string]bool{}
+	for i, targetNode := range cnf.Targets {
+		// a fresh copy of the top level, decoding into it must not touch the other targets
+		targetCnf := newDefaultConfig()
+		errUnmarshalTarget := yaml.Unmarshal(yamlBytes, &targetCnf)
+		if errUnmarshalTarget != nil {
+			return nil, errUnmarshalTarget
+		}
+		targetCnf.Targets = nil
+		errDecode := targetNode.Decode(targetCnf)
+		if errDecode != nil {
+			return nil, fmt.Errorf("targets[%d]: %s", i, errDecode)
+		}
+		if targetCnf.Name == "" || targetCnf.Name == cnf.Name {
+			return nil, fmt.Errorf("targets[%d] needs a name of its own", i)
+		}
+		if names[targetCnf.Name] {
+			return nil, errors.New("duplicate target name: " + targetCnf.Name)
+		}
+		names[targetCnf.Name] = true
+		if len(targetCnf.Targets) > 0 {
+			return nil, errors.New("targets can not be nested in target " + targetCnf.Name)
+		}
+		// inherited state must not be shared
+		if targetCnf.StateDir != "" && targetCnf.StateDir == cnf.StateDir {
+			targetCnf.StateDir = filepath.Join(cnf.StateDir, targetCnf.Name)
+		}
+		if targetCnf.History.Dir != "" && targetCnf.History.Dir == cnf.History.Dir {
+			targetCnf.History.Dir = filepath.Join(cnf.History.Dir, targetCnf.Name)
+		}
+		targetConf, errTarget := targetCnf.toConfig()
+		if errTarget != nil {
+			return nil, errors.New("target " + targetCnf.Name + ": " + errTarget.Error())
+		}
+		conf.Targets = append(conf.Targets, targetConf)
+	}
+	return conf, nil
+}
 
-	conf = &Config{
+func (cnf *config) toConfig() (conf *Config, err error) {
+	conf = cnf.mapConfig()
+	errTarget := conf.loadTarget(cnf.Target)
+	if errTarget != nil {
+		return nil, errTarget
+	}
+	return conf, nil
+}
+
+func (cnf *config) mapConfig() *Config {
+	return &Config{
+		Name:              cnf.Name,
 		Concurrency:       cnf.Concurrency,
 		Addr:              cnf.Addr,
+		APIToken:          cnf.APIToken,
 		Ignore:            cnf.Ignore,
 		IgnoreQueriesWith: cnf.IgnoreQueriesWith,
 		IgnoreAllQueries:  cnf.IgnoreAllQueries,
@@ -93,13 +555,36 @@ func Load(yamlBytes []byte) (conf *Config, err error) {
 		GroupHeader:       cnf.GroupHeader,
 		Agent:             cnf.Agent,
 		SchemaRoot:        cnf.SchemaRoot,
+		StateDir:          cnf.StateDir,
+		Baseline:          cnf.Baseline,
+		Resume:            cnf.Resume,
+		Once:              cnf.Once,
+		Politeness:        cnf.Politeness,
+		Retry:             cnf.Retry,
+		Sitemap:           cnf.Sitemap,
+		ExternalLinks:     cnf.ExternalLinks,
+		Forbidden:         cnf.Forbidden,
+		Assets:            cnf.Assets,
+		QualityGate:       cnf.QualityGate,
+		History:           cnf.History,
+		Schedule:          cnf.Schedule,
+		HTTP:              cnf.HTTP,
+		Auth:              cnf.Auth,
+		Normalize:         cnf.Normalize,
+		Rules:             cnf.Rules,
+		ExplainLinks:      cnf.ExplainLinks,
+		Budget:            cnf.Budget,
+		Priority:          cnf.Priority,
+		Frontier:          cnf.Frontier,
 	}
+}
 
-	switch cnf.Target.(type) {
+func (conf *Config) loadTarget(target interface{}) error {
+	switch target.(type) {
 	case string:
-		conf.Target.BaseURL = cnf.Target.(string)
+		conf.Target.BaseURL = target.(string)
 	case map[string]interface{}:
-		for key, v := range cnf.Target.(map[string]interface{}) {
+		for key, v := range target.(map[string]interface{}) {
 			key = strings.ToLower(key)
 			switch key {
 			case "baseurl":
@@ -107,7 +592,7 @@ func Load(yamlBytes []byte) (conf *Config, err error) {
 				case string:
 					conf.Target.BaseURL = v.(string)
 				default:
-					return nil, errors.New("illegal type for target.BaseURL")
+					return errors.New("illegal type for target.BaseURL")
 				}
 			case "paths":
 				switch v.(type) {
@@ -116,7 +601,7 @@ func Load(yamlBytes []byte) (conf *Config, err error) {
 						conf.Target.Paths = append(conf.Target.Paths, fmt.Sprint(p))
 					}
 				default:
-					return nil, errors.New("illegal type for target.Paths")
+					return errors.New("illegal type for target.Paths")
 				}
 
 			}
@@ -125,7 +610,7 @@ func Load(yamlBytes []byte) (conf *Config, err error) {
 	if len(conf.Target.Paths) == 0 {
 		baseURL, errURL := url.Parse(conf.Target.BaseURL)
 		if errURL != nil {
-			return nil, errors.New("target/target.baseurl can not be parsed: " + errURL.Error())
+			return errors.New("target/target.baseurl can not be parsed: " + errURL.Error())
 		}
 		if baseURL.Path == "" {
 			conf.Target.Paths = []string{"/"}
@@ -136,7 +621,7 @@ func Load(yamlBytes []byte) (conf *Config, err error) {
 		}
 	}
 	if conf.Target.BaseURL == "" {
-		return nil, errors.New("target base url must not be empty")
+		return errors.New("target base url must not be empty")
 	}
-	return
+	return nil
 }
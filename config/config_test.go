@@ -2,8 +2,11 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v3"
 )
 
 const (
@@ -21,6 +24,9 @@ paging: false
 agent: foomo-walker
 address: ":3001"
 schemaroot: htmlschema/example/schema/bestbytes
+politeness:
+  requestspersecond: 2.5
+  slowduration: 2s
 ...
 `
 	confComplexMinimal = `
@@ -39,6 +45,9 @@ func TestLoad(t *testing.T) {
 	cnf, errCnf := Load([]byte(confComplexTarget))
 	assert.NoError(t, errCnf)
 	assert.Equal(t, "https://www.bestbytes.de", cnf.Target.BaseURL)
+	assert.Equal(t, 2.5, cnf.Politeness.RequestsPerSecond)
+	assert.Equal(t, time.Second*2, cnf.Politeness.SlowDuration)
+	assert.Equal(t, time.Second*30, cnf.Politeness.MaxBackoff)
 
 	cnf, errCnf = Load([]byte(confComplexMinimal))
 	assert.NoError(t, errCnf)
@@ -50,3 +59,104 @@ func TestLoad(t *testing.T) {
 	assert.Equal(t, "https://www.bestbytes.de", cnf.Target.BaseURL)
 	assert.Equal(t, []string{"/foo"}, cnf.Target.Paths)
 }
+
+const confTargets = `
+---
+concurrency: 2
+agent: foomo-walker
+statedir: /var/lib/walker
+assets:
+  maxsizebytype:
+    image: 1000
+schedule:
+  interval: 1h
+targets:
+  - name: de
+    target: https://www.example.de
+    concurrency: 4
+    assets:
+      maxsizebytype:
+        font: 500
+  - name: en
+    target:
+      baseurl: https://www.example.com
+      paths:
+        - /en
+    schedule:
+      cron: "0 3 * * *"
+...
+`
+
+func TestLoadTargets(t *testing.T) {
+	cnf, errCnf := Load([]byte(confTargets))
+	assert.NoError(t, errCnf)
+	targets := cnf.TargetConfigs()
+	assert.Len(t, targets, 2)
+	de, en := targets[0], targets[1]
+	assert.Equal(t, "de", de.Name)
+	assert.Equal(t, "https://www.example.de", de.Target.BaseURL)
+	assert.Equal(t, 4, de.Concurrency)
+	assert.Equal(t, "/var/lib/walker/de", de.StateDir)
+	assert.Equal(t, map[string]int64{"image": 1000, "font": 500}, de.Assets.MaxSizeByType)
+	assert.Equal(t, time.Hour, de.Schedule.Interval)
+
+	assert.Equal(t, []string{"/en"}, en.Target.Paths)
+	assert.Equal(t, 2, en.Concurrency)
+	assert.Equal(t, "foomo-walker", en.Agent)
+	// overrides must not leak into other targets
+	assert.Equal(t, map[string]int64{"image": 1000}, en.Assets.MaxSizeByType)
+	assert.Equal(t, Schedule{Interval: time.Hour, Cron: "0 3 * * *"}, en.Schedule)
+
+	_, errDuplicate := Load([]byte(`
+targets:
+  - name: de
+    target: https://www.example.de
+  - name: de
+    target: https://www.example.com
+`))
+	assert.Error(t, errDuplicate)
+	_, errNoName := Load([]byte(`
+targets:
+  - target: https://www.example.de
+`))
+	assert.Error(t, errNoName)
+
+	single, errSingle := Load([]byte(confComplexMinimal))
+	assert.NoError(t, errSingle)
+	assert.Equal(t, []*Config{single}, single.TargetConfigs())
+}
+
+func TestRedacted(t *testing.T) {
+	cnf, errCnf := Load([]byte(`
+target: https://stage.example.com
+apitoken: t0ken
+auth:
+  username: stage
+  password: secret
+  bearertoken: b3arer
+  headers:
+    x-api-key: key
+  login:
+    username: shopper
+    password: formsecret
+    fields:
+      pin: p1n
+targets:
+  - name: de
+    target: https://stage.example.de
+`))
+	require.NoError(t, errCnf)
+	yamlBytes, errMarshal := yaml.Marshal(cnf.Redacted())
+	require.NoError(t, errMarshal)
+	dump := string(yamlBytes)
+	for _, secret := range []string{"secret", "b3arer", "key: key", "formsecret", "t0ken", "p1n"} {
+		assert.NotContains(t, dump, secret)
+	}
+	assert.Contains(t, dump, "username: stage")
+	// the config itself keeps its credentials
+	assert.Equal(t, "secret", cnf.Auth.Password)
+	assert.Equal(t, "key", cnf.Auth.Headers["x-api-key"])
+	assert.Equal(t, "p1n", cnf.Auth.Login.Fields["pin"])
+	assert.Equal(t, "secret", cnf.Targets[0].Auth.Password)
+	assert.Equal(t, "t0ken", cnf.Targets[0].APIToken)
+}
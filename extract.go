@@ -36,6 +36,24 @@ func ExtractStructure(doc *goquery.Document) (s vo.Structure, err error) {
 			}
 		}
 	})
+	doc.Find("link[rel=alternate][hreflang]").Each(func(i int, sel *goquery.Selection) {
+		attrHref, attrHrefOK := sel.Attr("href")
+		attrHreflang, _ := sel.Attr("hreflang")
+		if attrHrefOK {
+			s.Hreflangs = append(s.Hreflangs, vo.Hreflang{
+				Lang: extractTrimText(attrHreflang),
+				Href: extractTrimText(attrHref),
+			})
+		}
+	})
+	doc.Find("img[src]").Each(func(i int, sel *goquery.Selection) {
+		attrSrc, _ := sel.Attr("src")
+		s.Images = append(s.Images, extractTrimText(attrSrc))
+	})
+	doc.Find("script[src]").Each(func(i int, sel *goquery.Selection) {
+		attrSrc, _ := sel.Attr("src")
+		s.Scripts = append(s.Scripts, extractTrimText(attrSrc))
+	})
 	doc.Find("script[type=\"application/ld+json\"]").Each(func(i int, sel *goquery.Selection) {
 		ld := &vo.LinkedData{}
 		errDoc := json.Unmarshal([]byte(sel.Text()), &ld)
@@ -2,6 +2,7 @@ package walker
 
 import (
 	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/foomo/walker/vo"
@@ -15,6 +16,10 @@ type linkLimitations struct {
 	ignorePathPrefixes  []string
 	includePathPrefixes []string
 	ignoreQueriesWith   []string
+	normalizer          *urlNormalizer
+	rules               linkRules
+	// why links were followed or not, nil if nobody wants to know
+	decisions map[string]vo.LinkDecision
 }
 
 func NormalizeLink(baseURL *url.URL, linkURL string) (normalizedLink *url.URL, err error) {
@@ -34,7 +39,8 @@ func NormalizeLink(baseURL *url.URL, linkURL string) (normalizedLink *url.URL, e
 	if link.Scheme == "" || link.Scheme == "//" {
 		link.Scheme = baseURL.Scheme
 	}
-	if baseURL.User != nil {
+	// do not hand out our credentials to other hosts
+	if baseURL.User != nil && link.Host == baseURL.Host {
 		link.User = baseURL.User
 	}
 	// it is beautiful now
@@ -51,77 +57,97 @@ func filterScrapeLinks(
 	robotsGroup *robotstxt.Group,
 ) (links map[string]int) {
 	links = map[string]int{}
-LinkLoop:
+	baseU := baseURL
+	if ll.normalizer != nil {
+		baseU = ll.normalizer.normalize(baseURL)
+	}
 	for linkURL := range linkList {
 		// ok, time to really look at that url
 		linkU, errParseLinkU := NormalizeLink(baseURL, linkURL)
-		if errParseLinkU == nil {
+		if errParseLinkU != nil {
+			continue
+		}
+		if ll.normalizer != nil {
+			linkU = ll.normalizer.normalize(linkU)
+		}
+		follow, reason := ll.decide(linkU, baseU, linkNextNormalized, linkPrevNormalized, robotsGroup)
+		if ll.decisions != nil {
+			ll.decisions[linkU.String()] = vo.LinkDecision{Follow: follow, Reason: reason}
+		}
+		if follow {
+			links[linkU.String()]++
+		}
+	}
+	return links
+}
 
-			// is it a pager link
-			if !ll.paging {
-				if linkNextNormalized == linkU.String() || linkPrevNormalized == linkU.String() {
-					continue LinkLoop
-				}
-			}
+// decide, if a link will be followed and tell why
+func (ll linkLimitations) decide(
+	linkU *url.URL,
+	baseURL *url.URL,
+	linkNextNormalized string,
+	linkPrevNormalized string,
+	robotsGroup *robotstxt.Group,
+) (follow bool, reason string) {
+	// is it a pager link
+	if !ll.paging {
+		if linkNextNormalized == linkU.String() || linkPrevNormalized == linkU.String() {
+			return false, "pager link"
+		}
+	}
 
-			if linkU.Host != baseURL.Host || linkU.Scheme != baseURL.Scheme {
-				// ignoring external links
-				continue LinkLoop
-			}
+	if linkU.Host != baseURL.Host || linkU.Scheme != baseURL.Scheme {
+		// ignoring external links
+		return false, "external link"
+	}
 
-			if ll.depth > 0 {
-				// too deep?
-				if len(strings.Split(linkU.Path, "/"))-1 > ll.depth {
-					continue LinkLoop
-				}
-			}
+	if ll.depth > 0 {
+		// too deep?
+		if len(strings.Split(linkU.Path, "/"))-1 > ll.depth {
+			return false, "deeper than " + strconv.Itoa(ll.depth)
+		}
+	}
 
-			// ignore path prefix
-			for _, ignorePrefix := range ll.ignorePathPrefixes {
-				if strings.HasPrefix(linkU.Path, ignorePrefix) {
-					continue LinkLoop
-				}
-			}
+	// robots say no
+	if robotsGroup != nil && !robotsGroup.Test(linkU.Path) {
+		return false, "disallowed by robots.txt"
+	}
 
-			// robots say no
-			if robotsGroup != nil && !robotsGroup.Test(linkU.Path) {
-				continue LinkLoop
-			}
+	// the first matching rule decides
+	if matched, ruleFollow, ruleReason := ll.rules.decide(linkU); matched {
+		return ruleFollow, ruleReason
+	}
 
-			// are we ignoring it, because of the query
-			if len(linkU.Query()) > 0 {
-				// it has a query
-				if ll.ignoreAllQueries {
-					// no queries in general
-					continue LinkLoop
-				} else {
-					// do we filter a query parameter
-					for _, ignoreP := range ll.ignoreQueriesWith {
-						for pName := range linkU.Query() {
-							if pName == ignoreP {
-								continue LinkLoop
-							}
-						}
-					}
-				}
-			}
+	// ignore path prefix
+	for _, ignorePrefix := range ll.ignorePathPrefixes {
+		if strings.HasPrefix(linkU.Path, ignorePrefix) {
+			return false, "ignored path prefix " + ignorePrefix
+		}
+	}
 
-			// are we looking at the path is it included in the paths
-			foundPath := false
-			for _, p := range ll.includePathPrefixes {
-				if strings.HasPrefix(linkU.Path, p) {
-					foundPath = true
-					break
+	// are we ignoring it, because of the query
+	if len(linkU.Query()) > 0 {
+		// it has a query
+		if ll.ignoreAllQueries {
+			// no queries in general
+			return false, "all queries are ignored"
+		}
+		// do we filter a query parameter
+		for _, ignoreP := range ll.ignoreQueriesWith {
+			for pName := range linkU.Query() {
+				if pName == ignoreP {
+					return false, "ignored query parameter " + ignoreP
 				}
 			}
-			if !foundPath {
-				// not in the scrape path
-				continue LinkLoop
-			}
-
-			links[linkU.String()]++
+		}
+	}
 
+	// are we looking at the path is it included in the paths
+	for _, p := range ll.includePathPrefixes {
+		if strings.HasPrefix(linkU.Path, p) {
+			return true, "in path " + p
 		}
 	}
-	return links
+	// not in the scrape path
+	return false, "not in the paths of the target"
 }
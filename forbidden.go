@@ -0,0 +1,101 @@
+package walker
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/foomo/walker/vo"
+)
+
+// forbiddenMatcher finds urls pointing to hosts, that must not be linked like stage or preview systems
+type forbiddenMatcher struct {
+	hosts    []string
+	prefixes []string
+	regexes  []*regexp.Regexp
+}
+
+// newForbiddenMatcher supports host globs like *.stage.example.com, url prefixes like
+// https://www.example.com/preview and regular expressions starting with ^
+func newForbiddenMatcher(patterns []string) (fm *forbiddenMatcher, err error) {
+	fm = &forbiddenMatcher{}
+	for _, pattern := range patterns {
+		switch true {
+		case strings.HasPrefix(pattern, "^"):
+			regex, errCompile := regexp.Compile(pattern)
+			if errCompile != nil {
+				return nil, errCompile
+			}
+			fm.regexes = append(fm.regexes, regex)
+		case strings.Contains(pattern, "://"):
+			fm.prefixes = append(fm.prefixes, pattern)
+		default:
+			_, errMatch := path.Match(pattern, "")
+			if errMatch != nil {
+				return nil, errMatch
+			}
+			fm.hosts = append(fm.hosts, strings.ToLower(pattern))
+		}
+	}
+	return fm, nil
+}
+
+func (fm *forbiddenMatcher) match(u *url.URL) (pattern string, ok bool) {
+	host := strings.ToLower(u.Hostname())
+	for _, hostPattern := range fm.hosts {
+		if matched, _ := path.Match(hostPattern, host); matched {
+			return hostPattern, true
+		}
+	}
+	s := u.String()
+	for _, prefix := range fm.prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return prefix, true
+		}
+	}
+	for _, regex := range fm.regexes {
+		if regex.MatchString(s) {
+			return regex.String(), true
+		}
+	}
+	return "", false
+}
+
+// validate all references of a page
+func (fm *forbiddenMatcher) validate(result vo.ScrapeResult, baseURL *url.URL, track trackForbiddenLink) (validations vo.Validations) {
+	validations = vo.Validations{}
+	check := func(kind, ref string) {
+		if ref == "" {
+			return
+		}
+		refU, errNormalize := NormalizeLink(baseURL, ref)
+		if errNormalize != nil {
+			return
+		}
+		if pattern, ok := fm.match(refU); ok {
+			validations.Error(vo.ValidationGroupForbidden, kind+" "+refU.String()+" matches "+pattern)
+			if track != nil {
+				track(kind, pattern)
+			}
+		}
+	}
+	for link := range result.Links {
+		if link == result.Structure.Canonical {
+			// that one is checked as a canonical
+			continue
+		}
+		check(vo.ForbiddenKindLink, link)
+	}
+	check(vo.ForbiddenKindCanonical, result.Structure.Canonical)
+	for _, hreflang := range result.Structure.Hreflangs {
+		check(vo.ForbiddenKindHreflang, hreflang.Href)
+	}
+	for _, image := range result.Structure.Images {
+		check(vo.ForbiddenKindImage, image)
+	}
+	for _, script := range result.Structure.Scripts {
+		check(vo.ForbiddenKindScript, script)
+	}
+	return validations
+}
@@ -0,0 +1,49 @@
+package walker
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/foomo/walker/vo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForbiddenMatcher(t *testing.T) {
+	fm, errFM := newForbiddenMatcher([]string{
+		"*.stage.example.com",
+		"https://www.example.com/preview",
+		"^https?://[^/]+:8080/",
+	})
+	assert.NoError(t, errFM)
+	baseURL, _ := url.Parse("https://www.example.com")
+	tracked := map[string]int{}
+	validations := fm.validate(vo.ScrapeResult{
+		Links: vo.LinkList{
+			"/fine":                              1,
+			"https://shop.stage.example.com/foo": 1,
+			"/preview/draft":                     1,
+			"https://www.example.com/canonical":  1,
+		},
+		Structure: vo.Structure{
+			Canonical: "https://www.example.com/canonical",
+			Hreflangs: []vo.Hreflang{{Lang: "de", Href: "http://www.example.com:8080/de"}},
+			Images:    []string{"https://cdn.stage.example.com/a.png"},
+			Scripts:   []string{"/app.js"},
+		},
+	}, baseURL, func(kind, pattern string) {
+		tracked[kind]++
+	})
+	assert.Len(t, validations, 4)
+	for _, v := range validations {
+		assert.Equal(t, vo.ValidationLevelError, v.Level)
+		assert.Equal(t, vo.ValidationGroupForbidden, v.Group)
+	}
+	assert.Equal(t, map[string]int{
+		vo.ForbiddenKindLink:     2,
+		vo.ForbiddenKindHreflang: 1,
+		vo.ForbiddenKindImage:    1,
+	}, tracked)
+
+	_, errFM = newForbiddenMatcher([]string{"^(broken"})
+	assert.Error(t, errFM)
+}
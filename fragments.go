@@ -0,0 +1,71 @@
+package walker
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/foomo/walker/vo"
+)
+
+// extractAnchors collects all fragment targets of a document - ids and named anchors
+func extractAnchors(doc *goquery.Document) []string {
+	anchorMap := map[string]bool{}
+	doc.Find("[id]").Each(func(i int, sel *goquery.Selection) {
+		id, _ := sel.Attr("id")
+		if id != "" {
+			anchorMap[id] = true
+		}
+	})
+	doc.Find("a[name]").Each(func(i int, sel *goquery.Selection) {
+		name, _ := sel.Attr("name")
+		if name != "" {
+			anchorMap[name] = true
+		}
+	})
+	anchors := make([]string, 0, len(anchorMap))
+	for anchor := range anchorMap {
+		anchors = append(anchors, anchor)
+	}
+	sort.Strings(anchors)
+	return anchors
+}
+
+// ignoreFragment tells us about fragments, that do not need a target
+func ignoreFragment(fragment string) bool {
+	return fragment == "" ||
+		// the spec scrolls to the top, even without an element
+		strings.EqualFold(fragment, "top") ||
+		// client side routing #!/foo or #/foo
+		strings.HasPrefix(fragment, "!") ||
+		strings.HasPrefix(fragment, "/")
+}
+
+// extractFragmentLinks returns normalized links with their fragments, in page links like #foo are resolved against pageURL
+func extractFragmentLinks(linkList vo.LinkList, baseURL *url.URL, pageURL string) vo.LinkList {
+	fragmentLinks := vo.LinkList{}
+	for link, count := range linkList {
+		parts := strings.SplitN(link, "#", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		fragment, errUnescape := url.PathUnescape(parts[1])
+		if errUnescape != nil {
+			fragment = parts[1]
+		}
+		if ignoreFragment(fragment) {
+			continue
+		}
+		target := pageURL
+		if parts[0] != "" {
+			linkU, errNormalize := NormalizeLink(baseURL, parts[0])
+			if errNormalize != nil {
+				continue
+			}
+			target = linkU.String()
+		}
+		fragmentLinks[target+"#"+fragment] += count
+	}
+	return fragmentLinks
+}
@@ -0,0 +1,39 @@
+package walker
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/foomo/walker/vo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractAnchors(t *testing.T) {
+	doc, errDoc := goquery.NewDocumentFromReader(strings.NewReader(`<html><body>
+		<h2 id="shipping">shipping</h2>
+		<a name="returns"></a>
+		<div id="">empty</div>
+	</body></html>`))
+	assert.NoError(t, errDoc)
+	assert.Equal(t, []string{"returns", "shipping"}, extractAnchors(doc))
+}
+
+func TestExtractFragmentLinks(t *testing.T) {
+	baseURL, _ := url.Parse("https://www.example.com")
+	assert.Equal(t, vo.LinkList{
+		"https://www.example.com/faq#shipping":  2,
+		"https://www.example.com/page#in page":  1,
+		"https://www.example.com/other#returns": 1,
+	}, extractFragmentLinks(vo.LinkList{
+		"/faq#shipping":                         1,
+		"https://www.example.com/faq#shipping":  1,
+		"#in%20page":                            1,
+		"https://www.example.com/other#returns": 1,
+		"#":                                     1,
+		"#top":                                  1,
+		"/app#!/route":                          1,
+		"/no-fragment":                          1,
+	}, baseURL, "https://www.example.com/page"))
+}
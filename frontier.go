@@ -0,0 +1,343 @@
+package walker
+
+import (
+	"bufio"
+	"container/heap"
+	"errors"
+	"io/ioutil"
+	"math"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/foomo/walker/config"
+	"github.com/foomo/walker/vo"
+)
+
+// Job is an url waiting to be fetched
+type Job struct {
+	URL string
+	// clicks from the start pages, -1 if not reached by clicks
+	Hops      int
+	Discovery vo.Discovery
+	// priority of the sitemap entry, 0 if the url is not in a sitemap
+	SitemapPriority float64
+}
+
+// PriorityFunc ranks jobs, jobs with higher priorities are fetched first
+type PriorityFunc func(job Job) float64
+
+type pathPriority struct {
+	regex    *regexp.Regexp
+	priority float64
+}
+
+// jobPriority is either the priority func of a walk or the one of the config
+type jobPriority struct {
+	paths         []pathPriority
+	sitemapWeight float64
+	priorityFunc  PriorityFunc
+}
+
+func newJobPriority(conf config.Priority, priorityFunc PriorityFunc) (jp *jobPriority, err error) {
+	jp = &jobPriority{
+		sitemapWeight: conf.SitemapWeight,
+		priorityFunc:  priorityFunc,
+	}
+	for _, p := range conf.Paths {
+		regex, errRegex := regexp.Compile(p.Regex)
+		if errRegex != nil {
+			return nil, errRegex
+		}
+		jp.paths = append(jp.paths, pathPriority{regex: regex, priority: p.Priority})
+	}
+	return jp, nil
+}
+
+func (jp *jobPriority) priority(job Job) float64 {
+	if jp.priorityFunc != nil {
+		return jp.priorityFunc(job)
+	}
+	priority := jp.sitemapWeight * job.SitemapPriority
+	if len(jp.paths) > 0 {
+		if jobU, errParse := url.Parse(job.URL); errParse == nil {
+			for _, p := range jp.paths {
+				if p.regex.MatchString(jobU.Path) {
+					priority += p.priority
+					break
+				}
+			}
+		}
+	}
+	return priority
+}
+
+type frontierJob struct {
+	url      string
+	priority float64
+	hops     int
+	// the order jobs were found in
+	seq   uint64
+	index int
+}
+
+// pendingFunc tells the current priority and hops of a spilled job, ok is false, if it is not pending anymore
+type pendingFunc func(jobURL string) (priority float64, hops int, ok bool)
+
+// frontier of pending jobs ordered by priority, then by hops and then by the order they were found in,
+// jobs beyond maxJobs are spilled to a file and come back in the order they were found in,
+// when the jobs in memory are done
+type frontier struct {
+	jobs       []*frontierJob
+	byURL      map[string]*frontierJob
+	seq        uint64
+	depthFirst bool
+	maxJobs    int
+	spillDir   string
+	spill      *spillFile
+	// spilled jobs are not updated, they get their priority and hops back from pending, nil keeps the spilled ones
+	pending pendingFunc
+	// the last problem with the spill file
+	err error
+}
+
+func newFrontier(depthFirst bool, maxJobs int, spillDir string, pending pendingFunc) *frontier {
+	return &frontier{
+		byURL:      map[string]*frontierJob{},
+		depthFirst: depthFirst,
+		maxJobs:    maxJobs,
+		spillDir:   spillDir,
+		pending:    pending,
+	}
+}
+
+// Len of the jobs in memory for container/heap, use size for all jobs
+func (f *frontier) Len() int {
+	return len(f.jobs)
+}
+
+// size of the frontier including the spilled jobs
+func (f *frontier) size() int {
+	if f.spill == nil {
+		return len(f.jobs)
+	}
+	return len(f.jobs) + f.spill.lines
+}
+
+// hopsOrder puts jobs, that were not reached by clicks, last
+func (f *frontier) hopsOrder(hops int) int {
+	switch {
+	case hops < 0:
+		return math.MaxInt32
+	case f.depthFirst:
+		return -hops
+	}
+	return hops
+}
+
+func (f *frontier) Less(i, j int) bool {
+	a, b := f.jobs[i], f.jobs[j]
+	if a.priority != b.priority {
+		return a.priority > b.priority
+	}
+	if hopsA, hopsB := f.hopsOrder(a.hops), f.hopsOrder(b.hops); hopsA != hopsB {
+		return hopsA < hopsB
+	}
+	return a.seq < b.seq
+}
+
+func (f *frontier) Swap(i, j int) {
+	f.jobs[i], f.jobs[j] = f.jobs[j], f.jobs[i]
+	f.jobs[i].index = i
+	f.jobs[j].index = j
+}
+
+// Push is for container/heap, use push
+func (f *frontier) Push(x interface{}) {
+	job := x.(*frontierJob)
+	job.index = len(f.jobs)
+	f.jobs = append(f.jobs, job)
+	f.byURL[job.url] = job
+}
+
+// Pop is for container/heap, use pop
+func (f *frontier) Pop() interface{} {
+	last := len(f.jobs) - 1
+	job := f.jobs[last]
+	f.jobs[last] = nil
+	f.jobs = f.jobs[:last]
+	delete(f.byURL, job.url)
+	job.index = -1
+	return job
+}
+
+// push a job or update the priority and the hops of a waiting one
+func (f *frontier) push(jobURL string, priority float64, hops int) {
+	if job, ok := f.byURL[jobURL]; ok {
+		job.priority = priority
+		job.hops = hops
+		heap.Fix(f, job.index)
+		return
+	}
+	if f.maxJobs > 0 && len(f.jobs) >= f.maxJobs && f.spillJob(jobURL, priority, hops) {
+		return
+	}
+	f.seq++
+	heap.Push(f, &frontierJob{
+		url:      jobURL,
+		priority: priority,
+		hops:     hops,
+		seq:      f.seq,
+	})
+}
+
+// pop the job, that is next
+func (f *frontier) pop() *frontierJob {
+	if len(f.jobs) == 0 {
+		f.unspill()
+	}
+	if len(f.jobs) == 0 {
+		return nil
+	}
+	return heap.Pop(f).(*frontierJob)
+}
+
+// requeue a popped job at its old place
+func (f *frontier) requeue(job *frontierJob) {
+	heap.Push(f, job)
+}
+
+// spillJob returns false, if the job has to stay in memory
+func (f *frontier) spillJob(jobURL string, priority float64, hops int) bool {
+	if f.spill == nil {
+		spill, errSpill := newSpillFile(f.spillDir)
+		if errSpill != nil {
+			f.err = errors.New("could not create a spill file, keeping all jobs in memory: " + errSpill.Error())
+			f.maxJobs = 0
+			return false
+		}
+		f.spill = spill
+	}
+	errWrite := f.spill.write(strconv.FormatFloat(priority, 'g', -1, 64) + "\t" + strconv.Itoa(hops) + "\t" + jobURL)
+	if errWrite != nil {
+		f.err = errors.New("could not spill a job, keeping all jobs in memory: " + errWrite.Error())
+		f.maxJobs = 0
+		return false
+	}
+	return true
+}
+
+// unspill fills the empty memory with spilled jobs
+func (f *frontier) unspill() {
+	if f.spill == nil || f.spill.lines == 0 {
+		return
+	}
+	lines, errRead := f.spill.read(f.maxJobs)
+	if errRead != nil {
+		// the scrape loop requeues lost jobs
+		f.err = errors.New("could not read spilled jobs: " + errRead.Error())
+		f.close()
+	}
+	for _, line := range lines {
+		parts := strings.SplitN(line, "\t", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		priority, errPriority := strconv.ParseFloat(parts[0], 64)
+		hops, errHops := strconv.Atoi(parts[1])
+		if errPriority != nil || errHops != nil {
+			continue
+		}
+		if _, queued := f.byURL[parts[2]]; queued {
+			continue
+		}
+		if f.pending != nil {
+			// hops and priorities may have changed, while the job was spilled
+			var ok bool
+			priority, hops, ok = f.pending(parts[2])
+			if !ok {
+				continue
+			}
+		}
+		f.seq++
+		heap.Push(f, &frontierJob{
+			url:      parts[2],
+			priority: priority,
+			hops:     hops,
+			seq:      f.seq,
+		})
+	}
+}
+
+// close removes the spill file
+func (f *frontier) close() {
+	if f.spill != nil {
+		f.spill.remove()
+		f.spill = nil
+	}
+}
+
+// spillFile is a queue of lines in a temp file
+type spillFile struct {
+	name     string
+	file     *os.File
+	writer   *bufio.Writer
+	readFile *os.File
+	reader   *bufio.Reader
+	// lines, that were written and not read yet
+	lines int
+}
+
+func newSpillFile(dir string) (sf *spillFile, err error) {
+	file, errCreate := ioutil.TempFile(dir, "walker-frontier-")
+	if errCreate != nil {
+		return nil, errCreate
+	}
+	readFile, errOpen := os.Open(file.Name())
+	if errOpen != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, errOpen
+	}
+	return &spillFile{
+		name:     file.Name(),
+		file:     file,
+		writer:   bufio.NewWriter(file),
+		readFile: readFile,
+		reader:   bufio.NewReader(readFile),
+	}, nil
+}
+
+func (sf *spillFile) write(line string) error {
+	_, errWrite := sf.writer.WriteString(line + "\n")
+	if errWrite != nil {
+		return errWrite
+	}
+	sf.lines++
+	return nil
+}
+
+// read up to max lines, all of them if max is 0
+func (sf *spillFile) read(max int) (lines []string, err error) {
+	errFlush := sf.writer.Flush()
+	if errFlush != nil {
+		return nil, errFlush
+	}
+	for sf.lines > 0 && (max == 0 || len(lines) < max) {
+		line, errRead := sf.reader.ReadString('\n')
+		if errRead != nil {
+			return lines, errRead
+		}
+		lines = append(lines, strings.TrimSuffix(line, "\n"))
+		sf.lines--
+	}
+	return lines, nil
+}
+
+func (sf *spillFile) remove() {
+	sf.file.Close()
+	sf.readFile.Close()
+	os.Remove(sf.name)
+}
@@ -0,0 +1,234 @@
+package walker
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/foomo/walker/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func popAll(f *frontier) (urls []string) {
+	for job := f.pop(); job != nil; job = f.pop() {
+		urls = append(urls, job.url)
+	}
+	return urls
+}
+
+func TestFrontier(t *testing.T) {
+	fill := func(f *frontier) *frontier {
+		f.push("/a/1", 0, 2)
+		f.push("/sitemap", 0, -1)
+		f.push("/a", 0, 1)
+		f.push("/b", 0, 1)
+		f.push("/a/1/x", 0, 3)
+		return f
+	}
+	assert.Equal(t, []string{"/a", "/b", "/a/1", "/a/1/x", "/sitemap"}, popAll(fill(newFrontier(false, 0, "", nil))))
+	assert.Equal(t, []string{"/a/1/x", "/a/1", "/a", "/b", "/sitemap"}, popAll(fill(newFrontier(true, 0, "", nil))))
+
+	// priorities come first, updates move jobs
+	f := fill(newFrontier(false, 0, "", nil))
+	f.push("/sitemap", 1, -1)
+	f.push("/a/1/x", 0, 1)
+	assert.Equal(t, 5, f.Len())
+	assert.Equal(t, []string{"/sitemap", "/a", "/b", "/a/1/x", "/a/1"}, popAll(f))
+
+	// a requeued job keeps its place
+	f = fill(newFrontier(false, 0, "", nil))
+	job := f.pop()
+	f.requeue(job)
+	assert.Equal(t, "/a", f.pop().url)
+}
+
+func TestFrontierSpill(t *testing.T) {
+	spillDir := t.TempDir()
+	f := newFrontier(false, 2, spillDir, nil)
+	f.push("/a", 0, 1)
+	f.push("/b", 0, 1)
+	f.push("/c", 1, 1)
+	f.push("/d", 0, 2)
+	f.push("/e", 0, 1)
+	assert.Equal(t, 2, f.Len())
+	assert.Equal(t, 5, f.size())
+	// jobs in memory first, then the spilled ones by priority, two at a time
+	assert.Equal(t, []string{"/a", "/b", "/c", "/d", "/e"}, popAll(f))
+	assert.Equal(t, 0, f.size())
+
+	f.push("/f", 0, 1)
+	f.push("/g", 0, 1)
+	f.push("/h", 0, 1)
+	files, _ := ioutil.ReadDir(spillDir)
+	assert.Len(t, files, 1)
+	f.close()
+	files, _ = ioutil.ReadDir(spillDir)
+	assert.Empty(t, files)
+	assert.Equal(t, 2, f.size())
+
+	// without a spill dir all jobs stay in memory
+	f = newFrontier(false, 1, filepath.Join(spillDir, "nope"), nil)
+	f.push("/a", 0, 1)
+	f.push("/b", 0, 1)
+	assert.Equal(t, 2, f.Len())
+	assert.Error(t, f.err)
+}
+
+func TestFrontierSpillRelax(t *testing.T) {
+	hops := map[string]int{"/a": 1, "/b": 1, "/c": 3, "/d": 3, "/e": 3}
+	pending := func(jobURL string) (float64, int, bool) {
+		hop, ok := hops[jobURL]
+		return 0, hop, ok
+	}
+	f := newFrontier(false, 2, t.TempDir(), pending)
+	defer f.close()
+	for _, jobURL := range []string{"/a", "/b", "/c", "/d", "/e"} {
+		f.push(jobURL, 0, hops[jobURL])
+	}
+	assert.Equal(t, 2, f.Len())
+	// a shorter path to a spilled job and a spilled job, that was dropped
+	hops["/d"] = 2
+	delete(hops, "/e")
+	assert.Equal(t, "/a", f.pop().url)
+	assert.Equal(t, "/b", f.pop().url)
+	job := f.pop()
+	assert.Equal(t, "/d", job.url)
+	assert.Equal(t, 2, job.hops)
+	assert.Equal(t, "/c", f.pop().url)
+	assert.Nil(t, f.pop())
+	assert.NoError(t, f.err)
+}
+
+func BenchmarkFrontier(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		f := newFrontier(false, 0, "", nil)
+		for j := 0; j < 10000; j++ {
+			f.push("/p/"+strconv.Itoa(j), float64(j%3), j%7)
+		}
+		for f.pop() != nil {
+		}
+	}
+}
+
+func BenchmarkFrontierSpill(b *testing.B) {
+	b.ReportAllocs()
+	spillDir := b.TempDir()
+	for i := 0; i < b.N; i++ {
+		f := newFrontier(false, 1000, spillDir, nil)
+		for j := 0; j < 10000; j++ {
+			f.push("/p/"+strconv.Itoa(j), float64(j%3), j%7)
+		}
+		for f.pop() != nil {
+		}
+		f.close()
+	}
+}
+
+func TestJobPriority(t *testing.T) {
+	jp, errPriority := newJobPriority(config.Priority{
+		Paths: []config.PathPriority{
+			{Regex: "^/products/", Priority: 10},
+			{Regex: "^/products/archive/", Priority: 100},
+			{Regex: "^/blog/", Priority: -5},
+		},
+		SitemapWeight: 2,
+	}, nil)
+	require.NoError(t, errPriority)
+	assert.Equal(t, 10.0, jp.priority(Job{URL: "http://example.com/products/archive/old"}))
+	assert.Equal(t, 11.0, jp.priority(Job{URL: "http://example.com/products/new", SitemapPriority: 0.5}))
+	assert.Equal(t, -5.0, jp.priority(Job{URL: "http://example.com/blog/post"}))
+	assert.Equal(t, 0.0, jp.priority(Job{URL: "http://example.com/"}))
+
+	jp, _ = newJobPriority(config.Priority{Paths: []config.PathPriority{{Regex: ".", Priority: 10}}}, func(job Job) float64 {
+		return float64(job.Hops)
+	})
+	assert.Equal(t, 3.0, jp.priority(Job{URL: "http://example.com/", Hops: 3}))
+
+	_, errRegex := newJobPriority(config.Priority{Paths: []config.PathPriority{{Regex: "("}}}, nil)
+	assert.Error(t, errRegex)
+}
+
+func TestCrawlOrder(t *testing.T) {
+	pages := map[string][]string{
+		"/":           {"/b", "/a", "/products"},
+		"/a":          {"/a/1"},
+		"/b":          {"/b/1"},
+		"/products":   {"/products/1"},
+		"/a/1":        {},
+		"/b/1":        {},
+		"/products/1": {},
+	}
+	mutex := sync.Mutex{}
+	requested := []string{}
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		links, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		mutex.Lock()
+		requested = append(requested, r.URL.Path)
+		mutex.Unlock()
+		w.Header().Set("Content-Type", "text/html")
+		html := "<html><body>"
+		for _, link := range links {
+			html += `<a href="` + link + `">` + link + `</a>`
+		}
+		w.Write([]byte(html + "</body></html>"))
+	}))
+	defer testServer.Close()
+
+	frontierConf := config.Frontier{}
+	walk := func(priority config.Priority, options ...WalkOption) []string {
+		requested = []string{}
+		w, errNew := New(WithRegisterer(prometheus.NewRegistry()))
+		require.NoError(t, errNew)
+		defer w.Stop()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+		defer cancel()
+		chanStatus, errWalk := w.WalkContext(ctx, &config.Config{
+			Target:       config.Target{BaseURL: testServer.URL, Paths: []string{"/"}},
+			IgnoreRobots: true,
+			Once:         true,
+			Concurrency:  1,
+			Priority:     priority,
+			Frontier:     frontierConf,
+		}, options...)
+		require.NoError(t, errWalk)
+		select {
+		case <-chanStatus:
+		case <-ctx.Done():
+			t.Fatal("walk did not complete")
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		return requested
+	}
+
+	assert.Equal(t, []string{"/", "/a", "/b", "/products", "/a/1", "/b/1", "/products/1"}, walk(config.Priority{}))
+	assert.Equal(t, []string{"/", "/products", "/products/1", "/a", "/b", "/a/1", "/b/1"}, walk(config.Priority{
+		Paths: []config.PathPriority{{Regex: "^/products", Priority: 1}},
+	}))
+	// ties stay breadth first
+	assert.Equal(t, []string{"/", "/b", "/b/1", "/a", "/products", "/a/1", "/products/1"}, walk(config.Priority{}, WithPriorityFunc(func(job Job) float64 {
+		if strings.HasPrefix(job.URL, testServer.URL+"/b") {
+			return 1
+		}
+		return 0
+	})))
+	assert.Equal(t, []string{"/", "/a", "/a/1", "/b", "/b/1", "/products", "/products/1"}, walk(config.Priority{DepthFirst: true}))
+
+	// jobs spilled to a file are not lost
+	frontierConf = config.Frontier{MaxJobs: 1, SpillDir: t.TempDir()}
+	assert.ElementsMatch(t, []string{"/", "/a", "/b", "/products", "/a/1", "/b/1", "/products/1"}, walk(config.Priority{}))
+}
@@ -0,0 +1,63 @@
+package walker
+
+import (
+	"net/url"
+	"strings"
+)
+
+// groupGuesser guesses the group of a page before fetching it from the pages, that were fetched before:
+// a page is in the group of the pages next to it or of the closest page above it, the root tells nothing
+type groupGuesser struct {
+	// path => group, "" for paths, that saw more than one group
+	groups map[string]string
+}
+
+func newGroupGuesser() *groupGuesser {
+	return &groupGuesser{groups: map[string]string{}}
+}
+
+// learn the group of a fetched page
+func (gg *groupGuesser) learn(pageURL, group string) {
+	u, errParse := url.Parse(pageURL)
+	if errParse != nil {
+		return
+	}
+	p := strings.TrimSuffix(u.Path, "/")
+	keys := []string{}
+	if p != "" {
+		// the pages below
+		keys = append(keys, p)
+	}
+	if i := strings.LastIndex(p, "/"); i > 0 {
+		// the pages next to it
+		keys = append(keys, p[:i+1])
+	}
+	for _, key := range keys {
+		if known, ok := gg.groups[key]; ok && known != group {
+			gg.groups[key] = ""
+			continue
+		}
+		gg.groups[key] = group
+	}
+}
+
+// guess the group of a page, ok is false, if there is no good guess
+func (gg *groupGuesser) guess(pageURL string) (group string, ok bool) {
+	u, errParse := url.Parse(pageURL)
+	if errParse != nil {
+		return "", false
+	}
+	p := strings.TrimSuffix(u.Path, "/")
+	for {
+		i := strings.LastIndex(p, "/")
+		if i <= 0 {
+			return "", false
+		}
+		for _, key := range []string{p[:i+1], p[:i]} {
+			if known, found := gg.groups[key]; found {
+				return known, known != ""
+			}
+		}
+		p = p[:i]
+	}
+}
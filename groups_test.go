@@ -0,0 +1,41 @@
+package walker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupGuesser(t *testing.T) {
+	gg := newGroupGuesser()
+	gg.learn("http://example.com/", "home")
+	gg.learn("http://example.com/shop", "category")
+	gg.learn("http://example.com/shop/shoes", "category")
+	gg.learn("http://example.com/p/1", "product")
+	gg.learn("http://example.com/blog/", "blog")
+	gg.learn("http://example.com/mixed/a", "a")
+	gg.learn("http://example.com/mixed/b", "b")
+	for pageURL, expected := range map[string]string{
+		// next to a product
+		"http://example.com/p/2": "product",
+		// below a category
+		"http://example.com/shop/shoes/red":   "category",
+		"http://example.com/shop/socks":       "category",
+		"http://example.com/blog/hello-world": "blog",
+		"http://example.com/mixed/a/x":        "a",
+	} {
+		group, ok := gg.guess(pageURL)
+		assert.True(t, ok, pageURL)
+		assert.Equal(t, expected, group, pageURL)
+	}
+	for _, pageURL := range []string{
+		// the root tells nothing
+		"http://example.com/about",
+		"http://example.com/",
+		// neither do different groups
+		"http://example.com/mixed/c",
+	} {
+		_, ok := gg.guess(pageURL)
+		assert.False(t, ok, pageURL)
+	}
+}
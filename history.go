@@ -0,0 +1,292 @@
+package walker
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/foomo/walker/vo"
+)
+
+const (
+	historyFileSuffix = ".json.gz"
+	historyTimeFormat = "20060102T150405.000000000Z"
+)
+
+// trend metrics
+const (
+	TrendMetricResults  = "results"
+	TrendMetricErrors   = "errors"
+	TrendMetricStatus   = "status"
+	TrendMetricDuration = "duration"
+	TrendMetricScore    = "score"
+)
+
+// TrendQuery selects a metric from the history, URL selects a single page, Group a group, otherwise the whole crawl
+type TrendQuery struct {
+	Metric string
+	// for the status metric
+	Code  int
+	Group string
+	URL   string
+	// last n crawls, 0 means all
+	Last int
+}
+
+// History keeps the last complete crawls on disk
+type History struct {
+	mutex sync.Mutex
+	dir   string
+	keep  int
+	// cached stats of all entries
+	stats []vo.HistoryStats
+}
+
+// NewHistory keeps up to keep crawls in dir
+func NewHistory(dir string, keep int) (h *History, err error) {
+	errMkdir := os.MkdirAll(dir, 0755)
+	if errMkdir != nil {
+		return nil, errMkdir
+	}
+	return &History{dir: dir, keep: keep}, nil
+}
+
+func newHistoryEntry(status vo.Status, t time.Time) vo.HistoryEntry {
+	entry := vo.HistoryEntry{
+		HistoryStats: vo.HistoryStats{
+			Time:      t,
+			Results:   len(status.Results),
+			Errors:    status.ErrorCount(),
+			Codes:     map[int]int{},
+			Durations: map[string]time.Duration{},
+			Scores:    map[string]float64{},
+		},
+		Pages: make(map[string]vo.HistoryPage, len(status.Results)),
+	}
+	durationCounts := map[string]int{}
+	scoreCounts := map[string]int{}
+	for targetURL, r := range status.Results {
+		page := vo.HistoryPage{
+			Code:     r.Code,
+			Duration: r.Duration,
+			Group:    r.Group,
+		}
+		entry.Codes[r.Code]++
+		entry.Durations[r.Group] += r.Duration
+		durationCounts[r.Group]++
+		if r.ValidationReport != nil {
+			score := r.ValidationReport.Score
+			page.Score = &score
+			entry.Scores[r.Group] += float64(score)
+			scoreCounts[r.Group]++
+		}
+		entry.Pages[targetURL] = page
+	}
+	for group, count := range durationCounts {
+		entry.Durations[group] /= time.Duration(count)
+	}
+	for group, count := range scoreCounts {
+		entry.Scores[group] /= float64(count)
+	}
+	return entry
+}
+
+func (h *History) files() (filenames []string, err error) {
+	fileInfos, errRead := ioutil.ReadDir(h.dir)
+	if errRead != nil {
+		return nil, errRead
+	}
+	for _, fileInfo := range fileInfos {
+		if strings.HasSuffix(fileInfo.Name(), historyFileSuffix) {
+			filenames = append(filenames, filepath.Join(h.dir, fileInfo.Name()))
+		}
+	}
+	// the names are timestamps
+	sort.Strings(filenames)
+	return filenames, nil
+}
+
+func (h *History) read(filename string) (entry vo.HistoryEntry, err error) {
+	f, errOpen := os.Open(filename)
+	if errOpen != nil {
+		return entry, errOpen
+	}
+	defer f.Close()
+	gzipReader, errGzip := gzip.NewReader(f)
+	if errGzip != nil {
+		return entry, errGzip
+	}
+	defer gzipReader.Close()
+	return entry, json.NewDecoder(gzipReader).Decode(&entry)
+}
+
+// Add a complete crawl and drop the ones, we do not want to keep
+func (h *History) Add(status vo.Status) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	errLoad := h.loadStats()
+	if errLoad != nil {
+		return errLoad
+	}
+	now := time.Now().UTC()
+	entry := newHistoryEntry(status, now)
+	filename := filepath.Join(h.dir, now.Format(historyTimeFormat)+historyFileSuffix)
+	f, errCreate := os.Create(filename + ".tmp")
+	if errCreate != nil {
+		return errCreate
+	}
+	gzipWriter := gzip.NewWriter(f)
+	errEncode := json.NewEncoder(gzipWriter).Encode(entry)
+	errClose := gzipWriter.Close()
+	f.Close()
+	if errEncode != nil {
+		return errEncode
+	}
+	if errClose != nil {
+		return errClose
+	}
+	errRename := os.Rename(filename+".tmp", filename)
+	if errRename != nil {
+		return errRename
+	}
+	h.stats = append(h.stats, entry.HistoryStats)
+	if h.keep > 0 {
+		filenames, errFiles := h.files()
+		if errFiles != nil {
+			return errFiles
+		}
+		for len(filenames) > h.keep {
+			errRemove := os.Remove(filenames[0])
+			if errRemove != nil {
+				return errRemove
+			}
+			filenames = filenames[1:]
+		}
+		if len(h.stats) > h.keep {
+			h.stats = h.stats[len(h.stats)-h.keep:]
+		}
+	}
+	return nil
+}
+
+func (h *History) loadStats() error {
+	if h.stats != nil {
+		return nil
+	}
+	filenames, errFiles := h.files()
+	if errFiles != nil {
+		return errFiles
+	}
+	h.stats = []vo.HistoryStats{}
+	for _, filename := range filenames {
+		entry, errRead := h.read(filename)
+		if errRead != nil {
+			// a broken file must not break the history
+			continue
+		}
+		h.stats = append(h.stats, entry.HistoryStats)
+	}
+	return nil
+}
+
+func lastN(n, length int) int {
+	if n > 0 && n < length {
+		return length - n
+	}
+	return 0
+}
+
+// Stats of the last n crawls, 0 means all
+func (h *History) Stats(n int) ([]vo.HistoryStats, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	errLoad := h.loadStats()
+	if errLoad != nil {
+		return nil, errLoad
+	}
+	return append([]vo.HistoryStats{}, h.stats[lastN(n, len(h.stats)):]...), nil
+}
+
+// Trend of a metric over the last crawls, crawls without a value for the metric are skipped
+func (h *History) Trend(query TrendQuery) (points []vo.TrendPoint, err error) {
+	points = []vo.TrendPoint{}
+	if query.URL == "" {
+		stats, errStats := h.Stats(query.Last)
+		if errStats != nil {
+			return nil, errStats
+		}
+		for _, s := range stats {
+			value, ok, errValue := statsValue(s, query)
+			if errValue != nil {
+				return nil, errValue
+			}
+			if ok {
+				points = append(points, vo.TrendPoint{Time: s.Time, Value: value})
+			}
+		}
+		return points, nil
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	filenames, errFiles := h.files()
+	if errFiles != nil {
+		return nil, errFiles
+	}
+	for _, filename := range filenames[lastN(query.Last, len(filenames)):] {
+		entry, errRead := h.read(filename)
+		if errRead != nil {
+			continue
+		}
+		page, ok := entry.Pages[query.URL]
+		if !ok {
+			continue
+		}
+		var value float64
+		switch query.Metric {
+		case TrendMetricStatus:
+			value = float64(page.Code)
+		case TrendMetricDuration:
+			value = page.Duration.Seconds()
+		case TrendMetricScore:
+			if page.Score == nil {
+				continue
+			}
+			value = float64(*page.Score)
+		default:
+			return nil, errors.New("unknown metric for a page: " + query.Metric)
+		}
+		points = append(points, vo.TrendPoint{Time: entry.Time, Value: value})
+	}
+	return points, nil
+}
+
+func statsValue(s vo.HistoryStats, query TrendQuery) (value float64, ok bool, err error) {
+	switch query.Metric {
+	case TrendMetricResults:
+		return float64(s.Results), true, nil
+	case TrendMetricErrors:
+		return float64(s.Errors), true, nil
+	case TrendMetricStatus:
+		return float64(s.Codes[query.Code]), true, nil
+	case TrendMetricDuration:
+		if query.Group == "" {
+			return 0, false, errors.New("the duration metric needs a group or an url")
+		}
+		d, ok := s.Durations[query.Group]
+		return d.Seconds(), ok, nil
+	case TrendMetricScore:
+		if query.Group == "" {
+			return 0, false, errors.New("the score metric needs a group or an url")
+		}
+		score, ok := s.Scores[query.Group]
+		return score, ok, nil
+	}
+	return 0, false, errors.New("unknown metric: " + query.Metric)
+}
@@ -0,0 +1,66 @@
+package walker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/foomo/walker/vo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func historyTestStatus(duration time.Duration, code int) vo.Status {
+	return vo.Status{
+		Results: map[string]vo.ScrapeResult{
+			"https://www.example.com/": {
+				TargetURL: "https://www.example.com/",
+				Code:      200,
+				Duration:  duration,
+				Group:     "home",
+			},
+			"https://www.example.com/foo": {
+				TargetURL: "https://www.example.com/foo",
+				Code:      code,
+				Duration:  duration * 2,
+				Group:     "product",
+			},
+		},
+	}
+}
+
+func TestHistory(t *testing.T) {
+	h, errHistory := NewHistory(t.TempDir(), 2)
+	require.NoError(t, errHistory)
+	require.NoError(t, h.Add(historyTestStatus(time.Second, 200)))
+	require.NoError(t, h.Add(historyTestStatus(time.Second*2, 404)))
+	require.NoError(t, h.Add(historyTestStatus(time.Second*3, 500)))
+
+	stats, errStats := h.Stats(0)
+	require.NoError(t, errStats)
+	// only two are kept
+	assert.Len(t, stats, 2)
+	assert.Equal(t, 1, stats[0].Codes[404])
+	assert.Equal(t, time.Second*4, stats[0].Durations["product"])
+
+	// the cache has to match a fresh read from disk
+	reloaded, errReload := NewHistory(h.dir, 2)
+	require.NoError(t, errReload)
+	reloadedStats, errReloadedStats := reloaded.Stats(1)
+	require.NoError(t, errReloadedStats)
+	require.Len(t, reloadedStats, 1)
+	assert.Equal(t, 1, reloadedStats[0].Codes[500])
+
+	groupTrend, errGroupTrend := reloaded.Trend(TrendQuery{Metric: TrendMetricDuration, Group: "home"})
+	require.NoError(t, errGroupTrend)
+	require.Len(t, groupTrend, 2)
+	assert.Equal(t, 2.0, groupTrend[0].Value)
+	assert.Equal(t, 3.0, groupTrend[1].Value)
+
+	urlTrend, errURLTrend := reloaded.Trend(TrendQuery{Metric: TrendMetricStatus, URL: "https://www.example.com/foo", Last: 1})
+	require.NoError(t, errURLTrend)
+	require.Len(t, urlTrend, 1)
+	assert.Equal(t, 500.0, urlTrend[0].Value)
+
+	_, errUnknown := reloaded.Trend(TrendQuery{Metric: "foo"})
+	assert.Error(t, errUnknown)
+}
@@ -0,0 +1,157 @@
+package walker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/foomo/walker/config"
+	"github.com/foomo/walker/vo"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHopsAndBudgets(t *testing.T) {
+	pages := map[string][]string{
+		"/":      {"/a", "/b"},
+		"/a":     {"/a/1"},
+		"/a/1":   {"/a/1/x"},
+		"/b":     {"/c", "/d"},
+		"/c":     {"/a/1/x"},
+		"/d":     {},
+		"/a/1/x": {},
+	}
+	fetchedMutex := sync.Mutex{}
+	fetched := map[string]int{}
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetchedMutex.Lock()
+		fetched[r.URL.Path]++
+		fetchedMutex.Unlock()
+		links, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		group := "page"
+		if strings.HasPrefix(r.URL.Path, "/a") {
+			group = "a"
+		}
+		w.Header().Set("X-Group", group)
+		w.Header().Set("Content-Type", "text/html")
+		html := "<html><body>"
+		for _, link := range links {
+			html += `<a href="` + link + `">` + link + `</a>`
+		}
+		w.Write([]byte(html + "</body></html>"))
+	}))
+	defer testServer.Close()
+
+	walk := func(budget config.Budget) vo.Status {
+		w, errNew := New(WithRegisterer(prometheus.NewRegistry()))
+		require.NoError(t, errNew)
+		defer w.Stop()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+		defer cancel()
+		chanStatus, errWalk := w.WalkContext(ctx, &config.Config{
+			Target:       config.Target{BaseURL: testServer.URL, Paths: []string{"/"}},
+			GroupHeader:  "X-Group",
+			IgnoreRobots: true,
+			Once:         true,
+			Concurrency:  2,
+			Budget:       budget,
+		})
+		require.NoError(t, errWalk)
+		select {
+		case status := <-chanStatus:
+			return status
+		case <-ctx.Done():
+			t.Fatal("walk did not complete")
+		}
+		return vo.Status{}
+	}
+
+	status := walk(config.Budget{})
+	assert.Len(t, status.Results, 7)
+	for path, expected := range map[string]int{"/": 0, "/a": 1, "/b": 1, "/a/1": 2, "/c": 2, "/a/1/x": 3} {
+		assert.Equal(t, expected, status.Results[testServer.URL+path].Hops, path)
+	}
+
+	status = walk(config.Budget{MaxHops: 2})
+	assert.Len(t, status.Results, 6)
+	assert.NotContains(t, status.Results, testServer.URL+"/a/1/x")
+
+	status = walk(config.Budget{MaxPages: 3})
+	assert.Len(t, status.Results, 3)
+	assert.Equal(t, "max pages 3", status.Budget)
+	assert.True(t, status.BudgetDropped > 0)
+
+	fetchedMutex.Lock()
+	fetched = map[string]int{}
+	fetchedMutex.Unlock()
+	status = walk(config.Budget{MaxPagesPerGroup: map[string]int{"a": 1}})
+	assert.Len(t, status.Results, 5)
+	assert.Equal(t, 2, status.BudgetDropped)
+	// /a/1 is below /a and its group is full before it is fetched
+	fetchedMutex.Lock()
+	assert.Equal(t, 0, fetched["/a/1"])
+	fetchedMutex.Unlock()
+}
+
+func TestHopsWithLinkListFilter(t *testing.T) {
+	pages := map[string][]string{
+		"/":    {"/a", "/b"},
+		"/a":   {"/a/1"},
+		"/a/1": {"/x"},
+		"/b":   {"/x"},
+		"/x":   {"/y"},
+		"/y":   {},
+	}
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		html := "<html><body>"
+		for _, link := range pages[r.URL.Path] {
+			html += `<a href="` + link + `">` + link + `</a>`
+		}
+		w.Write([]byte(html + "</body></html>"))
+	}))
+	defer testServer.Close()
+	linkListFilter := func(baseURL, docURL *url.URL, doc *goquery.Document) (ll vo.LinkList, err error) {
+		ll = vo.LinkList{}
+		doc.Find("a").Each(func(i int, s *goquery.Selection) {
+			href, _ := s.Attr("href")
+			ll[baseURL.String()+href]++
+		})
+		return ll, nil
+	}
+	w, errNew := New(WithRegisterer(prometheus.NewRegistry()))
+	require.NoError(t, errNew)
+	defer w.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	// depth first reaches /x through /a/1, before /b shows the shorter path
+	chanStatus, errWalk := w.WalkContext(ctx, &config.Config{
+		Target:       config.Target{BaseURL: testServer.URL, Paths: []string{"/"}},
+		IgnoreRobots: true,
+		Once:         true,
+		Concurrency:  1,
+		Priority:     config.Priority{DepthFirst: true},
+	}, WithLinkListFilter(linkListFilter))
+	require.NoError(t, errWalk)
+	select {
+	case status := <-chanStatus:
+		assert.Len(t, status.Results, 6)
+		for path, expected := range map[string]int{"/": 0, "/a": 1, "/b": 1, "/a/1": 2, "/x": 2, "/y": 3} {
+			assert.Equal(t, expected, status.Results[testServer.URL+path].Hops, path)
+		}
+		assert.Equal(t, vo.LinkList{testServer.URL + "/y": 1}, status.Results[testServer.URL+"/x"].FilteredLinks)
+	case <-ctx.Done():
+		t.Fatal("walk did not complete")
+	}
+}
@@ -3,7 +3,7 @@ package htmlschema
 type Attribute struct {
 	Name  string
 	Value string
-	Rules map[string]AttributeRule
+	Rules map[string]AttributeRule `json:"-"`
 }
 
 type Element struct {
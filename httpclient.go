@@ -0,0 +1,233 @@
+package walker
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/foomo/walker/config"
+	"github.com/foomo/walker/vo"
+)
+
+// httpClientSettings are config.HTTP with loaded certificates and a parsed proxy
+type httpClientSettings struct {
+	conf      config.HTTP
+	tlsConfig *tls.Config
+	proxy     func(*http.Request) (*url.URL, error)
+	// headers for requests to the host of the target
+	headers   http.Header
+	host      string
+	inspector *tlsInspector
+}
+
+func newHTTPClientSettings(conf config.HTTP, baseURL string) (settings *httpClientSettings, err error) {
+	baseU, errParse := url.Parse(baseURL)
+	if errParse != nil {
+		return nil, errParse
+	}
+	defaults := config.DefaultHTTP()
+	for _, d := range []struct {
+		value        *time.Duration
+		defaultValue time.Duration
+	}{
+		{&conf.Timeout, defaults.Timeout},
+		{&conf.DialTimeout, defaults.DialTimeout},
+		{&conf.KeepAlive, defaults.KeepAlive},
+		{&conf.TLSHandshakeTimeout, defaults.TLSHandshakeTimeout},
+		{&conf.ResponseHeaderTimeout, defaults.ResponseHeaderTimeout},
+		{&conf.IdleConnTimeout, defaults.IdleConnTimeout},
+	} {
+		if *d.value == 0 {
+			*d.value = d.defaultValue
+		}
+	}
+	if conf.MaxIdleConns == 0 {
+		conf.MaxIdleConns = defaults.MaxIdleConns
+	}
+	if conf.MaxIdleConnsPerHost == 0 {
+		conf.MaxIdleConnsPerHost = defaults.MaxIdleConnsPerHost
+	}
+	settings = &httpClientSettings{
+		conf:    conf,
+		proxy:   http.ProxyFromEnvironment,
+		headers: http.Header{},
+		host:    baseU.Host,
+		tlsConfig: &tls.Config{
+			InsecureSkipVerify: conf.InsecureSkipVerify,
+		},
+	}
+	if len(conf.CAFiles) > 0 {
+		roots, errRoots := x509.SystemCertPool()
+		if errRoots != nil || roots == nil {
+			roots = x509.NewCertPool()
+		}
+		for _, caFile := range conf.CAFiles {
+			pemBytes, errRead := ioutil.ReadFile(caFile)
+			if errRead != nil {
+				return nil, errRead
+			}
+			if !roots.AppendCertsFromPEM(pemBytes) {
+				return nil, errors.New("no certificates found in " + caFile)
+			}
+		}
+		settings.tlsConfig.RootCAs = roots
+	}
+	if conf.ClientCertFile != "" || conf.ClientKeyFile != "" {
+		cert, errCert := tls.LoadX509KeyPair(conf.ClientCertFile, conf.ClientKeyFile)
+		if errCert != nil {
+			return nil, errCert
+		}
+		settings.tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	if conf.Proxy != "" {
+		proxyURL, errProxy := url.Parse(conf.Proxy)
+		if errProxy != nil {
+			return nil, errProxy
+		}
+		settings.proxy = http.ProxyURL(proxyURL)
+	}
+	for name, value := range conf.Headers {
+		settings.headers.Set(name, value)
+	}
+	settings.inspector = newTLSInspector(settings.tlsConfig.RootCAs)
+	return settings, nil
+}
+
+func (s *httpClientSettings) equals(other *httpClientSettings) bool {
+	return other != nil && s.host == other.host && reflect.DeepEqual(s.conf, other.conf)
+}
+
+// strip the headers for the target from a request, that was redirected away from it
+func (s *httpClientSettings) strip(req *http.Request) {
+	if req.URL.Host == s.host {
+		return
+	}
+	for name := range s.headers {
+		req.Header.Del(name)
+	}
+}
+
+// newClient follows redirects, auth may be nil
+func (s *httpClientSettings) newClient(useCookies bool, auth *authenticator) *http.Client {
+	client := &http.Client{
+		Timeout: s.conf.Timeout,
+		Transport: &http.Transport{
+			Proxy: s.proxy,
+			DialContext: (&net.Dialer{
+				Timeout:   s.conf.DialTimeout,
+				KeepAlive: s.conf.KeepAlive,
+			}).DialContext,
+			MaxIdleConns:          s.conf.MaxIdleConns,
+			MaxIdleConnsPerHost:   s.conf.MaxIdleConnsPerHost,
+			IdleConnTimeout:       s.conf.IdleConnTimeout,
+			TLSHandshakeTimeout:   s.conf.TLSHandshakeTimeout,
+			ResponseHeaderTimeout: s.conf.ResponseHeaderTimeout,
+			ExpectContinueTimeout: 1 * time.Second,
+			TLSClientConfig:       s.tlsConfig.Clone(),
+		},
+
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > 9 {
+				return errors.New("stopped after 10 redirects")
+			}
+			// net/http copies all headers of the first request, but only drops some of them for other hosts
+			auth.strip(req)
+			s.strip(req)
+			c := req.Context()
+			vias := getRedirectsFromRequest(req)
+			newR := req.WithContext(
+				context.WithValue(
+					c,
+					contextKeyRedirects{}, append(vias, vo.Redirect{
+						Code: req.Response.StatusCode,
+						URL:  req.URL.String(),
+					})))
+			*req = *newR
+			return nil
+		},
+	}
+	if useCookies {
+		cookieJar, _ := cookiejar.New(nil)
+		client.Jar = cookieJar
+	}
+	return client
+}
+
+// tlsInspector verifies certificates, even if the client does not, results are cached per certificate and host
+type tlsInspector struct {
+	roots *x509.CertPool
+	mutex sync.Mutex
+	cache map[string]vo.TLSInfo
+}
+
+func newTLSInspector(roots *x509.CertPool) *tlsInspector {
+	return &tlsInspector{
+		roots: roots,
+		cache: map[string]vo.TLSInfo{},
+	}
+}
+
+var tlsVersions = map[uint16]string{
+	tls.VersionTLS10: "TLS 1.0",
+	tls.VersionTLS11: "TLS 1.1",
+	tls.VersionTLS12: "TLS 1.2",
+	tls.VersionTLS13: "TLS 1.3",
+}
+
+func (ti *tlsInspector) inspect(state *tls.ConnectionState, host string, now time.Time) *vo.TLSInfo {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return nil
+	}
+	leaf := state.PeerCertificates[0]
+	key := host + " " + leaf.SerialNumber.String() + " " + leaf.Issuer.String()
+	ti.mutex.Lock()
+	info, ok := ti.cache[key]
+	ti.mutex.Unlock()
+	if !ok {
+		info = vo.TLSInfo{
+			Subject:  leaf.Subject.CommonName,
+			Issuer:   leaf.Issuer.CommonName,
+			DNSNames: leaf.DNSNames,
+			NotAfter: leaf.NotAfter,
+		}
+		intermediates := x509.NewCertPool()
+		for _, cert := range state.PeerCertificates[1:] {
+			intermediates.AddCert(cert)
+		}
+		_, errVerify := leaf.Verify(x509.VerifyOptions{
+			DNSName:       host,
+			Roots:         ti.roots,
+			Intermediates: intermediates,
+			CurrentTime:   now,
+		})
+		if errVerify != nil {
+			info.Problems = append(info.Problems, errVerify.Error())
+		}
+		ti.mutex.Lock()
+		ti.cache[key] = info
+		ti.mutex.Unlock()
+	}
+	info.Version = tlsVersions[state.Version]
+	return &info
+}
+
+// tlsError tells, if a request failed, because of a certificate
+func tlsError(err error) bool {
+	var errUnknownAuthority x509.UnknownAuthorityError
+	var errCertificateInvalid x509.CertificateInvalidError
+	var errHostname x509.HostnameError
+	var errSystemRoots x509.SystemRootsError
+	return errors.As(err, &errUnknownAuthority) ||
+		errors.As(err, &errCertificateInvalid) ||
+		errors.As(err, &errHostname) ||
+		errors.As(err, &errSystemRoots)
+}
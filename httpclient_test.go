@@ -0,0 +1,117 @@
+package walker
+
+import (
+	"context"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/foomo/walker/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testHTTPClientSettings(conf config.HTTP) *httpClientSettings {
+	settings, errSettings := newHTTPClientSettings(conf, "")
+	if errSettings != nil {
+		panic(errSettings)
+	}
+	return settings
+}
+
+func TestHTTPClientTLS(t *testing.T) {
+	acceptLanguage := ""
+	testServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptLanguage = r.Header.Get("Accept-Language")
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><title>tls</title></html>"))
+	}))
+	defer testServer.Close()
+	baseURL, _ := url.Parse(testServer.URL)
+	scrapeWith := func(conf config.HTTP) scrapeResultAndClient {
+		settings, errSettings := newHTTPClientSettings(conf, testServer.URL)
+		require.NoError(t, errSettings)
+		cp := newClientPool(1, "test", false, settings, nil)
+		chanResult := make(chan scrapeResultAndClient, 1)
+		scrape(context.Background(), cp.clients[0], testServer.URL+"/", baseURL, "", nil, nil, nil, retryPolicy(config.Retry{MaxAttempts: 1}), nil, chanResult)
+		return <-chanResult
+	}
+
+	// verified by default
+	result := scrapeWith(config.HTTP{}).result
+	assert.Equal(t, 0, result.Code)
+	require.NotNil(t, result.TLS)
+	assert.Len(t, result.TLS.Problems, 1)
+
+	// still reported, when skipping the verification
+	result = scrapeWith(config.HTTP{InsecureSkipVerify: true}).result
+	assert.Equal(t, http.StatusOK, result.Code)
+	require.NotNil(t, result.TLS)
+	assert.Len(t, result.TLS.Problems, 1)
+	assert.Equal(t, "TLS 1.3", result.TLS.Version)
+
+	// trusted with a ca bundle
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: testServer.Certificate().Raw}), 0644))
+	result = scrapeWith(config.HTTP{CAFiles: []string{caFile}, Headers: map[string]string{"accept-language": "de-DE"}}).result
+	assert.Equal(t, http.StatusOK, result.Code)
+	require.NotNil(t, result.TLS)
+	assert.Empty(t, result.TLS.Problems)
+	assert.Equal(t, "de-DE", acceptLanguage)
+
+	_, errCA := newHTTPClientSettings(config.HTTP{CAFiles: []string{filepath.Join(t.TempDir(), "nope.pem")}}, testServer.URL)
+	assert.Error(t, errCA)
+}
+
+func TestHTTPClientHeaders(t *testing.T) {
+	otherHeaders := http.Header{}
+	otherServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		otherHeaders = r.Header.Clone()
+	}))
+	defer otherServer.Close()
+	targetHeaders := http.Header{}
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		targetHeaders = r.Header.Clone()
+		http.Redirect(w, r, otherServer.URL+"/", http.StatusFound)
+	}))
+	defer testServer.Close()
+	settings, errSettings := newHTTPClientSettings(config.HTTP{Headers: map[string]string{"X-Feature": "beta"}}, testServer.URL)
+	require.NoError(t, errSettings)
+	pc := newClientPool(1, "test", false, settings, nil).clients[0]
+	get := func(targetURL string) {
+		req, errRequest := pc.newRequest(http.MethodGet, targetURL)
+		require.NoError(t, errRequest)
+		resp, errGet := pc.client.Do(req)
+		require.NoError(t, errGet)
+		resp.Body.Close()
+	}
+
+	// redirected away from the target
+	get(testServer.URL + "/")
+	assert.Equal(t, "beta", targetHeaders.Get("X-Feature"))
+	assert.Empty(t, otherHeaders.Get("X-Feature"))
+	assert.Equal(t, "test", otherHeaders.Get("User-Agent"))
+
+	// like a sitemap on another host
+	otherHeaders = http.Header{}
+	get(otherServer.URL + "/sitemap.xml")
+	assert.Empty(t, otherHeaders.Get("X-Feature"))
+	assert.Equal(t, "test", otherHeaders.Get("User-Agent"))
+}
+
+func TestHTTPClientProxy(t *testing.T) {
+	proxied := ""
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+	}))
+	defer proxy.Close()
+	client := testHTTPClientSettings(config.HTTP{Proxy: proxy.URL}).newClient(false, nil)
+	resp, errGet := client.Get("http://www.example.com/foo")
+	require.NoError(t, errGet)
+	resp.Body.Close()
+	assert.Equal(t, "http://www.example.com/foo", proxied)
+}
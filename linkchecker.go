@@ -0,0 +1,268 @@
+package walker
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/foomo/walker/config"
+	"github.com/foomo/walker/vo"
+)
+
+// do not download the internet, when falling back to GET
+const linkCheckMaxBody = 1024 * 1024
+
+type linkCheckerOptions struct {
+	concurrency       int
+	requestsPerSecond float64
+	timeout           time.Duration
+	// how long results are cached
+	maxAge time.Duration
+	// url prefixes, that are not checked
+	ignore []string
+	// maximum number of bytes to read, when using GET
+	maxBody int64
+	// fall back to GET, if HEAD does not tell us the size
+	measure bool
+}
+
+func externalLinkCheckerOptions(conf config.ExternalLinks) linkCheckerOptions {
+	return linkCheckerOptions{
+		concurrency:       conf.Concurrency,
+		requestsPerSecond: conf.RequestsPerSecond,
+		timeout:           conf.Timeout,
+		maxAge:            conf.MaxAge,
+		ignore:            conf.Ignore,
+		maxBody:           linkCheckMaxBody,
+	}
+}
+
+// linkChecker checks links with its own workers, limits and a cache, that outlives scrape loops
+type linkChecker struct {
+	client  *http.Client
+	agent   string
+	auth    *authenticator
+	maxAge  time.Duration
+	ignore  []string
+	maxBody int64
+	measure bool
+	mutex   sync.Mutex
+	thr     *throttle
+	results map[string]vo.LinkCheck
+	pending map[string]bool
+	queue   []linkCheckJob
+	wakeUp  chan struct{}
+	done    chan struct{}
+}
+
+// linkCheckJob is a link and the context of the walk, that found it
+type linkCheckJob struct {
+	ctx  context.Context
+	link string
+}
+
+// newLinkChecker checks without sessions, but sends basic auth and tokens to the host of the target
+func newLinkChecker(agent string, settings *httpClientSettings, auth *authenticator, options linkCheckerOptions) *linkChecker {
+	client := settings.newClient(false, auth)
+	if options.timeout > 0 {
+		client.Timeout = options.timeout
+	}
+	lc := &linkChecker{
+		client:  client,
+		agent:   agent,
+		auth:    auth,
+		maxAge:  options.maxAge,
+		ignore:  options.ignore,
+		maxBody: options.maxBody,
+		measure: options.measure,
+		thr:     newThrottle(config.Politeness{RequestsPerSecond: options.requestsPerSecond}, 0),
+		results: map[string]vo.LinkCheck{},
+		pending: map[string]bool{},
+		wakeUp:  make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	concurrency := options.concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	for i := 0; i < concurrency; i++ {
+		go lc.work()
+	}
+	return lc
+}
+
+// check queues links, that have not been checked recently, cancelling ctx aborts their checks
+func (lc *linkChecker) check(ctx context.Context, links []string) {
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+	now := time.Now()
+	queued := false
+LinkLoop:
+	for _, link := range links {
+		for _, ignorePrefix := range lc.ignore {
+			if strings.HasPrefix(link, ignorePrefix) {
+				continue LinkLoop
+			}
+		}
+		if lc.pending[link] {
+			continue
+		}
+		if existing, ok := lc.results[link]; ok && (lc.maxAge == 0 || now.Sub(existing.Time) < lc.maxAge) {
+			continue
+		}
+		lc.pending[link] = true
+		lc.queue = append(lc.queue, linkCheckJob{ctx: ctx, link: link})
+		queued = true
+	}
+	if queued {
+		lc.signal()
+	}
+}
+
+func (lc *linkChecker) signal() {
+	select {
+	case lc.wakeUp <- struct{}{}:
+	default:
+	}
+}
+
+func (lc *linkChecker) getResults() map[string]vo.LinkCheck {
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+	results := make(map[string]vo.LinkCheck, len(lc.results))
+	for link, result := range lc.results {
+		results[link] = result
+	}
+	return results
+}
+
+func (lc *linkChecker) stop() {
+	close(lc.done)
+}
+
+func (lc *linkChecker) next() (job linkCheckJob, wait time.Duration, ok bool) {
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+	for len(lc.queue) > 0 && lc.queue[0].ctx.Err() != nil {
+		// the walk is over
+		delete(lc.pending, lc.queue[0].link)
+		lc.queue = lc.queue[1:]
+	}
+	if len(lc.queue) == 0 {
+		return job, 0, false
+	}
+	job = lc.queue[0]
+	lc.queue = lc.queue[1:]
+	if len(lc.queue) > 0 {
+		// there is more work for the others
+		lc.signal()
+	}
+	host := ""
+	if u, errParse := url.Parse(job.link); errParse == nil {
+		host = u.Host
+	}
+	return job, lc.thr.reserve(host, time.Now()), true
+}
+
+func (lc *linkChecker) work() {
+	for {
+		job, wait, ok := lc.next()
+		if !ok {
+			select {
+			case <-lc.done:
+				return
+			case <-lc.wakeUp:
+				continue
+			}
+		}
+		select {
+		case <-lc.done:
+			return
+		case <-job.ctx.Done():
+		case <-time.After(wait):
+		}
+		result := lc.checkLink(job.ctx, job.link)
+		lc.mutex.Lock()
+		delete(lc.pending, job.link)
+		// a cancelled check tells nothing about the link
+		if job.ctx.Err() == nil {
+			lc.results[job.link] = result
+			if u, errParse := url.Parse(job.link); errParse == nil {
+				lc.thr.feedback(u.Host, result.Code, result.Duration)
+			}
+		}
+		lc.mutex.Unlock()
+	}
+}
+
+func (lc *linkChecker) request(ctx context.Context, method, link string) (result vo.LinkCheck) {
+	result = vo.LinkCheck{
+		URL:    link,
+		Method: method,
+		Time:   time.Now(),
+		Length: -1,
+	}
+	req, errRequest := http.NewRequestWithContext(ctx, method, link, nil)
+	if errRequest != nil {
+		result.Error = errRequest.Error()
+		return result
+	}
+	req.Header.Set("User-Agent", lc.agent)
+	if lc.auth != nil {
+		lc.auth.authorize(req)
+	}
+	resp, errDo := lc.client.Do(req)
+	if errDo != nil {
+		result.Error = errDo.Error()
+		result.Duration = time.Since(result.Time)
+		return result
+	}
+	bodyLength, _ := io.Copy(ioutil.Discard, io.LimitReader(resp.Body, lc.maxBody))
+	resp.Body.Close()
+	result.Duration = time.Since(result.Time)
+	result.Code = resp.StatusCode
+	result.Status = resp.Status
+	result.ContentType = resp.Header.Get("Content-Type")
+	result.CacheControl = resp.Header.Get("Cache-Control")
+	result.Expires = resp.Header.Get("Expires")
+	result.ETag = resp.Header.Get("ETag")
+	result.LastModified = resp.Header.Get("Last-Modified")
+	result.Length = resp.ContentLength
+	if result.Length < 0 && method == http.MethodGet {
+		result.Length = bodyLength
+	}
+	return result
+}
+
+// checkLink tries HEAD first and falls back to GET, because a lot of servers do not like HEAD
+func (lc *linkChecker) checkLink(ctx context.Context, link string) vo.LinkCheck {
+	result := lc.request(ctx, http.MethodHead, link)
+	if ctx.Err() != nil || (!result.Broken() && (!lc.measure || result.Length >= 0)) {
+		return result
+	}
+	return lc.request(ctx, http.MethodGet, link)
+}
+
+// externalLinks returns the links of a page, that are pointing to other hosts
+func externalLinks(linkList vo.LinkList, baseURL *url.URL) []string {
+	links := []string{}
+	for link := range linkList {
+		linkU, errNormalize := NormalizeLink(baseURL, link)
+		if errNormalize != nil {
+			continue
+		}
+		if linkU.Scheme != "http" && linkU.Scheme != "https" {
+			continue
+		}
+		if linkU.Host == baseURL.Host {
+			continue
+		}
+		links = append(links, linkU.String())
+	}
+	return links
+}
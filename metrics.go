@@ -1,61 +1,134 @@
 package walker
 
-import "github.com/prometheus/client_golang/prometheus"
+import (
+	"strconv"
+	"sync"
+
+	"github.com/foomo/walker/vo"
+	"github.com/prometheus/client_golang/prometheus"
+)
 
 type trackValidationScore func(group, path string, score int)
 type trackValidationPenalty func(group, path, validationType string, score int)
+type trackForbiddenLink func(kind, pattern string)
+type trackTiming func(group string, timing vo.Timing)
+
+// prometheus const label, that tells walkers apart
+const prometheusLabelWalker = "walker"
+
+// DefaultName of walkers, that were not given a name
+const DefaultName = "default"
+
+// unnamed walkers on the same registerer get distinct names, otherwise their restarts would reset each other's metrics
+var defaultNames = struct {
+	sync.Mutex
+	counts map[prometheus.Registerer]int
+}{counts: map[prometheus.Registerer]int{}}
+
+// defaultName returns DefaultName for the first unnamed walker on a registerer and DefaultName-2, DefaultName-3, ... for the next ones
+func defaultName(registerer prometheus.Registerer) string {
+	defaultNames.Lock()
+	defer defaultNames.Unlock()
+	defaultNames.counts[registerer]++
+	count := defaultNames.counts[registerer]
+	if count == 1 {
+		return DefaultName
+	}
+	return DefaultName + "-" + strconv.Itoa(count)
+}
+
+type metrics struct {
+	summaryVec             *prometheus.SummaryVec
+	counterVec             *prometheus.CounterVec
+	totalCounter           prometheus.Counter
+	progressGaugeOpen      prometheus.Gauge
+	progressGaugeComplete  prometheus.Gauge
+	counterVecStatus       *prometheus.CounterVec
+	trackValidationScore   trackValidationScore
+	trackValidationPenalty trackValidationPenalty
+	trackForbiddenLink     trackForbiddenLink
+	trackTiming            trackTiming
+}
+
+// register a collector, if an equal one is already registered, that one is returned
+func register(registerer prometheus.Registerer, collector prometheus.Collector) (prometheus.Collector, error) {
+	errRegister := registerer.Register(collector)
+	if errRegister != nil {
+		if errAlreadyRegistered, ok := errRegister.(prometheus.AlreadyRegisteredError); ok {
+			return errAlreadyRegistered.ExistingCollector, nil
+		}
+		return nil, errRegister
+	}
+	return collector, nil
+}
 
-func setupMetrics() (
-	summaryVec *prometheus.SummaryVec,
-	counterVec *prometheus.CounterVec,
-	totalCounter prometheus.Counter,
-	progressGaugeOpen prometheus.Gauge,
-	progressGaugeComplete prometheus.Gauge,
-	counterVecStatus *prometheus.CounterVec,
-	trackValidationScore trackValidationScore,
-	trackValidationPenalty trackValidationPenalty,
-) {
+// setupMetrics registers the metrics of a walker with a const label for its name,
+// walkers with the same name on the same registerer share their metrics
+func setupMetrics(registerer prometheus.Registerer, name string) (m *metrics, err error) {
 
 	const (
 		prometheusLabelGroup          = "group"
 		prometheusLabelStatus         = "status"
 		prometheusLabelPath           = "path"
 		prometheusLabelValidationType = "type"
+		prometheusLabelKind           = "kind"
+		prometheusLabelPattern        = "pattern"
+		prometheusLabelPhase          = "phase"
 	)
 
-	summaryVec = prometheus.NewSummaryVec(
+	constLabels := prometheus.Labels{prometheusLabelWalker: name}
+
+	// the first error sticks, the collector is returned as it is to keep the type assertions simple
+	registerCollector := func(c prometheus.Collector) prometheus.Collector {
+		if err != nil {
+			return c
+		}
+		collector, errRegister := register(registerer, c)
+		if errRegister != nil {
+			err = errRegister
+			return c
+		}
+		return collector
+	}
+
+	m = &metrics{}
+
+	m.summaryVec = registerCollector(prometheus.NewSummaryVec(
 		prometheus.SummaryOpts{
-			Name:       "walker_scrape_durations_seconds",
-			Help:       "scrape duration whole request time including streaming of body",
-			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
+			Name:        "walker_scrape_durations_seconds",
+			Help:        "scrape duration whole request time including streaming of body",
+			Objectives:  map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
+			ConstLabels: constLabels,
 		},
 		[]string{prometheusLabelGroup},
-	)
+	)).(*prometheus.SummaryVec)
 
-	schemaValidationScoreVec := prometheus.NewSummaryVec(
+	schemaValidationScoreVec := registerCollector(prometheus.NewSummaryVec(
 		prometheus.SummaryOpts{
-			Name:       "walker_validation_score",
-			Help:       "html schema score for groups in paths",
-			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
+			Name:        "walker_validation_score",
+			Help:        "html schema score for groups in paths",
+			Objectives:  map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
+			ConstLabels: constLabels,
 		},
 		[]string{prometheusLabelGroup, prometheusLabelPath},
-	)
-	trackValidationScore = func(group, path string, score int) {
+	)).(*prometheus.SummaryVec)
+	m.trackValidationScore = func(group, path string, score int) {
 		schemaValidationScoreVec.With(prometheus.Labels{
 			prometheusLabelGroup: group,
 			prometheusLabelPath:  path,
 		}).Observe(float64(score))
 	}
 
-	schemaValidationPenaltyVec := prometheus.NewSummaryVec(
+	schemaValidationPenaltyVec := registerCollector(prometheus.NewSummaryVec(
 		prometheus.SummaryOpts{
-			Name:       "walker_validation_penalty",
-			Help:       "html schema score for groups and validation types in paths",
-			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
+			Name:        "walker_validation_penalty",
+			Help:        "html schema score for groups and validation types in paths",
+			Objectives:  map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
+			ConstLabels: constLabels,
 		},
 		[]string{prometheusLabelGroup, prometheusLabelPath, prometheusLabelValidationType},
-	)
-	trackValidationPenalty = func(group, path, validationType string, score int) {
+	)).(*prometheus.SummaryVec)
+	m.trackValidationPenalty = func(group, path, validationType string, score int) {
 		schemaValidationPenaltyVec.With(prometheus.Labels{
 			prometheusLabelGroup:          group,
 			prometheusLabelPath:           path,
@@ -63,47 +136,78 @@ func setupMetrics() (
 		}).Observe(float64(score))
 	}
 
-	counterVec = prometheus.NewCounterVec(
+	forbiddenLinksCounterVec := registerCollector(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "walker_forbidden_links_total",
+			Help:        "references to forbidden hosts and urls",
+			ConstLabels: constLabels,
+		},
+		[]string{prometheusLabelKind, prometheusLabelPattern},
+	)).(*prometheus.CounterVec)
+	m.trackForbiddenLink = func(kind, pattern string) {
+		forbiddenLinksCounterVec.With(prometheus.Labels{
+			prometheusLabelKind:    kind,
+			prometheusLabelPattern: pattern,
+		}).Inc()
+	}
+
+	timingHistogramVec := registerCollector(prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:        "walker_scrape_phase_duration_seconds",
+			Help:        "request phases dns, connect, tls, ttfb, body and total",
+			Buckets:     []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+			ConstLabels: constLabels,
+		},
+		[]string{prometheusLabelGroup, prometheusLabelPhase},
+	)).(*prometheus.HistogramVec)
+	m.trackTiming = func(group string, timing vo.Timing) {
+		for phase, duration := range timing.Phases() {
+			timingHistogramVec.With(prometheus.Labels{
+				prometheusLabelGroup: group,
+				prometheusLabelPhase: phase,
+			}).Observe(duration.Seconds())
+		}
+	}
+
+	m.counterVec = registerCollector(prometheus.NewCounterVec(
 		prometheus.CounterOpts{
-			Name: "walker_scrape_running_total",
-			Help: "Number of scrapes in scan.",
+			Name:        "walker_scrape_running_total",
+			Help:        "Number of scrapes in scan.",
+			ConstLabels: constLabels,
 		},
 		[]string{prometheusLabelGroup, prometheusLabelStatus},
-	)
+	)).(*prometheus.CounterVec)
 
-	totalCounter = prometheus.NewCounter(prometheus.CounterOpts{
-		Name: "walker_scrape_counter_total",
-		Help: "number of scrapes since start of walker",
-	})
+	m.totalCounter = registerCollector(prometheus.NewCounter(prometheus.CounterOpts{
+		Name:        "walker_scrape_counter_total",
+		Help:        "number of scrapes since start of walker",
+		ConstLabels: constLabels,
+	})).(prometheus.Counter)
 
-	progressGaugeOpen = prometheus.NewGauge(
+	m.progressGaugeOpen = registerCollector(prometheus.NewGauge(
 		prometheus.GaugeOpts{
-			Name: "walker_progress_gauge_open",
-			Help: "progress open to scrape",
+			Name:        "walker_progress_gauge_open",
+			Help:        "progress open to scrape",
+			ConstLabels: constLabels,
 		},
-	)
+	)).(prometheus.Gauge)
 
-	progressGaugeComplete = prometheus.NewGauge(
+	m.progressGaugeComplete = registerCollector(prometheus.NewGauge(
 		prometheus.GaugeOpts{
-			Name: "walker_progress_gauge_complete",
-			Help: "progress complete scrapes",
+			Name:        "walker_progress_gauge_complete",
+			Help:        "progress complete scrapes",
+			ConstLabels: constLabels,
 		},
-	)
+	)).(prometheus.Gauge)
 
-	counterVecStatus = prometheus.NewCounterVec(prometheus.CounterOpts{
-		Name: "walker_progress_status_code_total",
-		Help: "status codes for running scrape",
-	}, []string{prometheusLabelStatus})
-
-	prometheus.MustRegister(
-		summaryVec,
-		counterVec,
-		totalCounter,
-		counterVecStatus,
-		progressGaugeOpen,
-		progressGaugeComplete,
-		schemaValidationScoreVec,
-		schemaValidationPenaltyVec,
-	)
-	return
+	m.counterVecStatus = registerCollector(prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:        "walker_progress_status_code_total",
+		Help:        "status codes for running scrape",
+		ConstLabels: constLabels,
+	}, []string{prometheusLabelStatus})).(*prometheus.CounterVec)
+
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
 }
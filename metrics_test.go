@@ -0,0 +1,105 @@
+package walker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/foomo/walker/config"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsPerWalker(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	walkerA, errA := New(WithRegisterer(registry), WithName("a"))
+	require.NoError(t, errA)
+	defer walkerA.Stop()
+	walkerB, errB := New(WithRegisterer(registry), WithName("b"))
+	require.NoError(t, errB)
+	defer walkerB.Stop()
+	// same name, same metrics
+	walkerA2, errA2 := New(WithRegisterer(registry), WithName("a"))
+	require.NoError(t, errA2)
+	defer walkerA2.Stop()
+	assert.Equal(t, "a", walkerA2.Name())
+
+	walkerA.metrics.totalCounter.Inc()
+	walkerA2.metrics.totalCounter.Inc()
+	walkerB.metrics.totalCounter.Inc()
+
+	families, errGather := registry.Gather()
+	require.NoError(t, errGather)
+	totals := map[string]float64{}
+	for _, family := range families {
+		if family.GetName() != "walker_scrape_counter_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == prometheusLabelWalker {
+					totals[label.GetValue()] = metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	assert.Equal(t, map[string]float64{"a": 2, "b": 1}, totals)
+}
+
+func TestMetricsDefaultNames(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body></body></html>"))
+	}))
+	defer testServer.Close()
+	registry := prometheus.NewRegistry()
+	first, errFirst := New(WithRegisterer(registry))
+	require.NoError(t, errFirst)
+	defer first.Stop()
+	second, errSecond := New(WithRegisterer(registry))
+	require.NoError(t, errSecond)
+	defer second.Stop()
+	assert.Equal(t, DefaultName, first.Name())
+	assert.Equal(t, DefaultName+"-2", second.Name())
+	// other registerers start over
+	other, errOther := New(WithRegisterer(prometheus.NewRegistry()))
+	require.NoError(t, errOther)
+	defer other.Stop()
+	assert.Equal(t, DefaultName, other.Name())
+
+	first.metrics.counterVecStatus.WithLabelValues("200").Inc()
+	// the restart of the second walker must not reset the metrics of the first one
+	chanLoopComplete, errWalk := second.WalkContext(context.Background(), &config.Config{
+		Target:       config.Target{BaseURL: testServer.URL, Paths: []string{"/"}},
+		IgnoreRobots: true,
+		Concurrency:  1,
+		Once:         true,
+	})
+	require.NoError(t, errWalk)
+	select {
+	case <-chanLoopComplete:
+	case <-time.After(time.Second * 10):
+		t.Fatal("the walk did not complete")
+	}
+
+	families, errGather := registry.Gather()
+	require.NoError(t, errGather)
+	statusCodes := map[string]float64{}
+	for _, family := range families {
+		if family.GetName() != "walker_progress_status_code_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == prometheusLabelWalker {
+					statusCodes[label.GetValue()] = metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	assert.Equal(t, map[string]float64{DefaultName: 1, DefaultName + "-2": 1}, statusCodes)
+}
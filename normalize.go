@@ -0,0 +1,202 @@
+package walker
+
+import (
+	"errors"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/foomo/walker/config"
+	"github.com/foomo/walker/vo"
+)
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// urlNormalizer turns the different spellings of a url into the one, that is used as a job
+type urlNormalizer struct {
+	conf          config.Normalize
+	stripNames    map[string]bool
+	stripPrefixes []string
+}
+
+func newURLNormalizer(conf config.Normalize) (n *urlNormalizer, err error) {
+	switch conf.TrailingSlash {
+	case "", "add", "remove":
+	default:
+		return nil, errors.New("normalize.trailingslash has to be add or remove, got: " + conf.TrailingSlash)
+	}
+	n = &urlNormalizer{
+		conf:       conf,
+		stripNames: map[string]bool{},
+	}
+	for _, name := range conf.StripQueryParameters {
+		if strings.HasSuffix(name, "*") {
+			n.stripPrefixes = append(n.stripPrefixes, strings.TrimSuffix(name, "*"))
+			continue
+		}
+		n.stripNames[name] = true
+	}
+	return n, nil
+}
+
+func (n *urlNormalizer) strip(name string) bool {
+	if n.stripNames[name] {
+		return true
+	}
+	for _, prefix := range n.stripPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// upperPercentEncoding turns %2f into %2F
+func upperPercentEncoding(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	b := []byte(s)
+	for i := 0; i < len(b)-2; i++ {
+		if b[i] == '%' && isHex(b[i+1]) && isHex(b[i+2]) {
+			b[i+1] = upperHex(b[i+1])
+			b[i+2] = upperHex(b[i+2])
+			i += 2
+		}
+	}
+	return string(b)
+}
+
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func upperHex(c byte) byte {
+	if c >= 'a' && c <= 'f' {
+		return c - 'a' + 'A'
+	}
+	return c
+}
+
+// normalize a copy of u
+func (n *urlNormalizer) normalize(u *url.URL) *url.URL {
+	nu := *u
+	nu.Fragment = ""
+	nu.RawFragment = ""
+	nu.ForceQuery = false
+	nu.Scheme = strings.ToLower(nu.Scheme)
+	nu.Host = strings.ToLower(nu.Host)
+	if port := nu.Port(); port != "" && port == defaultPorts[nu.Scheme] {
+		nu.Host = strings.TrimSuffix(nu.Host, ":"+port)
+	}
+
+	if strings.Contains(strings.ToLower(nu.RawPath), "%2f") {
+		// decoding would turn an encoded slash into a path separator
+		nu.RawPath = upperPercentEncoding(nu.RawPath)
+	} else {
+		p := nu.Path
+		if n.conf.LowerCasePath {
+			p = strings.ToLower(p)
+		}
+		for _, indexFile := range n.conf.IndexFiles {
+			if strings.HasSuffix(p, "/"+indexFile) {
+				p = strings.TrimSuffix(p, indexFile)
+				break
+			}
+		}
+		switch n.conf.TrailingSlash {
+		case "add":
+			if p != "" && !strings.HasSuffix(p, "/") && path.Ext(p) == "" {
+				p += "/"
+			}
+		case "remove":
+			if len(p) > 1 && strings.HasSuffix(p, "/") {
+				p = strings.TrimRight(p, "/")
+			}
+		}
+		if p == "" && nu.Host != "" {
+			p = "/"
+		}
+		// without a raw path the path is encoded the same way for every spelling
+		nu.Path = p
+		nu.RawPath = ""
+	}
+
+	if nu.RawQuery != "" {
+		parts := []string{}
+		for _, part := range strings.Split(nu.RawQuery, "&") {
+			if part == "" {
+				continue
+			}
+			name := part
+			if i := strings.Index(part, "="); i > -1 {
+				name = part[:i]
+			}
+			if unescapedName, errUnescape := url.QueryUnescape(name); errUnescape == nil {
+				name = unescapedName
+			}
+			if n.strip(name) {
+				continue
+			}
+			parts = append(parts, upperPercentEncoding(part))
+		}
+		if n.conf.SortQuery {
+			sort.Strings(parts)
+		}
+		nu.RawQuery = strings.Join(parts, "&")
+	}
+	return &nu
+}
+
+// normalizeString falls back to s, if it can not be parsed
+func (n *urlNormalizer) normalizeString(s string) string {
+	u, errParse := url.Parse(s)
+	if errParse != nil {
+		return s
+	}
+	return n.normalize(u).String()
+}
+
+// normalizeLinks of a page, spellings of links to the target, that differ from their normalized url, are counted in variants
+func (n *urlNormalizer) normalizeLinks(baseURL *url.URL, links vo.LinkList, variants map[string]map[string]int) vo.LinkList {
+	normalizedLinks := vo.LinkList{}
+	baseHost := n.normalize(baseURL).Host
+	spellings := map[string]string{}
+	for link, count := range links {
+		linkU, errNormalize := NormalizeLink(baseURL, link)
+		if errNormalize != nil {
+			continue
+		}
+		normalizedLinkU := n.normalize(linkU)
+		normalizedLink := normalizedLinkU.String()
+		normalizedLinks[normalizedLink] += count
+		if spelling := linkU.String(); spelling != normalizedLink && normalizedLinkU.Host == baseHost {
+			spellings[spelling] = normalizedLink
+		}
+	}
+	// every page counts once per spelling
+	for spelling, normalizedLink := range spellings {
+		if variants[normalizedLink] == nil {
+			variants[normalizedLink] = map[string]int{}
+		}
+		variants[normalizedLink][spelling]++
+	}
+	return normalizedLinks
+}
+
+// normalizeFragmentLinks keys fragment links like the results and keeps their fragments
+func (n *urlNormalizer) normalizeFragmentLinks(fragmentLinks vo.LinkList) vo.LinkList {
+	normalizedFragmentLinks := make(vo.LinkList, len(fragmentLinks))
+	for fragmentLink, count := range fragmentLinks {
+		parts := strings.SplitN(fragmentLink, "#", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		normalizedFragmentLinks[n.normalizeString(parts[0])+"#"+parts[1]] += count
+	}
+	return normalizedFragmentLinks
+}
@@ -0,0 +1,123 @@
+package walker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/foomo/walker/config"
+	"github.com/foomo/walker/reports"
+	"github.com/foomo/walker/vo"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	normalizer, errNormalizer := newURLNormalizer(config.Normalize{
+		TrailingSlash:        "remove",
+		LowerCasePath:        true,
+		SortQuery:            true,
+		StripQueryParameters: []string{"utm_*", "gclid"},
+		IndexFiles:           []string{"index.html"},
+	})
+	require.NoError(t, errNormalizer)
+	for raw, expected := range map[string]string{
+		"HTTP://Example.COM:80":                 "http://example.com/",
+		"https://example.com:443/a/":            "https://example.com/a",
+		"https://example.com:8443/a":            "https://example.com:8443/a",
+		"https://example.com/A/B/":              "https://example.com/a/b",
+		"https://example.com/a?utm_source=x":    "https://example.com/a",
+		"https://example.com/a?c=2&b=1&gclid=x": "https://example.com/a?b=1&c=2",
+		"https://example.com/a?q=%c3%a4":        "https://example.com/a?q=%C3%A4",
+		"https://example.com/%7euser":           "https://example.com/~user",
+		"https://example.com/docs/index.html":   "https://example.com/docs",
+		"https://example.com/a%2fb":             "https://example.com/a%2Fb",
+		"https://example.com/a?":                "https://example.com/a",
+		"https://example.com/a#top":             "https://example.com/a",
+	} {
+		u, errParse := url.Parse(raw)
+		require.NoError(t, errParse)
+		assert.Equal(t, expected, normalizer.normalize(u).String(), raw)
+	}
+
+	// nothing but the safe steps by default
+	defaultNormalizer, _ := newURLNormalizer(config.Normalize{})
+	assert.Equal(t, "http://example.com/A/?c=2&b=1", defaultNormalizer.normalizeString("http://EXAMPLE.com:80/A/?c=2&b=1"))
+
+	adding, _ := newURLNormalizer(config.Normalize{TrailingSlash: "add"})
+	assert.Equal(t, "http://example.com/a/", adding.normalizeString("http://example.com/a"))
+	assert.Equal(t, "http://example.com/a.pdf", adding.normalizeString("http://example.com/a.pdf"))
+
+	_, errTrailingSlash := newURLNormalizer(config.Normalize{TrailingSlash: "sometimes"})
+	assert.Error(t, errTrailingSlash)
+}
+
+func TestNormalizeLinks(t *testing.T) {
+	normalizer, _ := newURLNormalizer(config.Normalize{TrailingSlash: "remove", SortQuery: true})
+	baseURL, _ := url.Parse("http://example.com/")
+	variants := map[string]map[string]int{}
+	links := normalizer.normalizeLinks(baseURL, vo.LinkList{"/a": 1, "/a/": 2, "/b?y=1&x=2": 1, "http://other.com/c/": 1}, variants)
+	assert.Equal(t, vo.LinkList{"http://example.com/a": 3, "http://example.com/b?x=2&y=1": 1, "http://other.com/c": 1}, links)
+	normalizer.normalizeLinks(baseURL, vo.LinkList{"/a/": 1}, variants)
+	assert.Equal(t, map[string]map[string]int{
+		"http://example.com/a":         {"http://example.com/a/": 2},
+		"http://example.com/b?x=2&y=1": {"http://example.com/b?y=1&x=2": 1},
+	}, variants)
+
+	ll := linkLimitations{includePathPrefixes: []string{"/"}, normalizer: normalizer}
+	assert.Equal(t, map[string]int{"http://example.com/a": 2}, filterScrapeLinks(vo.LinkList{"/a": 1, "/a/": 1}, baseURL, "", "", ll, nil))
+}
+
+func TestNormalizeFragmentLinks(t *testing.T) {
+	normalizer, _ := newURLNormalizer(config.Normalize{TrailingSlash: "remove", StripQueryParameters: []string{"utm_*"}})
+	assert.Equal(t, vo.LinkList{"http://example.com/a#foo": 2, "http://example.com/#top-news": 1}, normalizer.normalizeFragmentLinks(vo.LinkList{
+		"http://example.com/a/#foo":                1,
+		"http://example.com/a?utm_source=mail#foo": 1,
+		"http://example.com/#top-news":             1,
+	}))
+
+	// fragment targets are found among the results
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`<html><body><a href="/a/#foo">foo</a><a href="/a/#bar">bar</a></body></html>`))
+		case "/a":
+			w.Write([]byte(`<html><body><h2 id="foo">foo</h2></body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer testServer.Close()
+	w, errNew := New(WithRegisterer(prometheus.NewRegistry()))
+	require.NoError(t, errNew)
+	defer w.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	chanStatus, errWalk := w.WalkContext(ctx, &config.Config{
+		Target:       config.Target{BaseURL: testServer.URL, Paths: []string{"/"}},
+		IgnoreRobots: true,
+		Once:         true,
+		Concurrency:  1,
+		Normalize:    config.Normalize{TrailingSlash: "remove"},
+	})
+	require.NoError(t, errWalk)
+	select {
+	case status := <-chanStatus:
+		assert.Equal(t, vo.LinkList{testServer.URL + "/a#foo": 1, testServer.URL + "/a#bar": 1}, status.Results[testServer.URL+"/"].FragmentLinks)
+		report, errReport := reports.Build("fragments", status)
+		require.NoError(t, errReport)
+		require.Len(t, report.Sections, 1)
+		require.Len(t, report.Sections[0].Items, 1)
+		assert.Equal(t, testServer.URL+"/a#bar", report.Sections[0].Items[0].URL)
+		require.Len(t, report.Sections[0].Notes, 1)
+		assert.True(t, strings.HasPrefix(report.Sections[0].Notes[0], "0 fragment links could not be checked"))
+	case <-ctx.Done():
+		t.Fatal("walk did not complete")
+	}
+}
@@ -0,0 +1,139 @@
+package walker
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/foomo/walker/config"
+)
+
+const (
+	// first step, when we start to back off
+	backoffMin = time.Second
+	// below this a backoff is dropped
+	backoffThreshold = time.Millisecond * 10
+)
+
+type hostThrottle struct {
+	backoff time.Duration
+	next    time.Time
+}
+
+// throttle keeps track of when we may send the next request to a host, retries of the workers share it with the scrape loop
+type throttle struct {
+	mutex        sync.Mutex
+	interval     time.Duration
+	crawlDelay   time.Duration
+	slowDuration time.Duration
+	maxBackoff   time.Duration
+	hosts        map[string]*hostThrottle
+}
+
+func newThrottle(politeness config.Politeness, crawlDelay time.Duration) *throttle {
+	t := &throttle{
+		slowDuration: politeness.SlowDuration,
+		maxBackoff:   politeness.MaxBackoff,
+		hosts:        map[string]*hostThrottle{},
+	}
+	if politeness.RequestsPerSecond > 0 {
+		t.interval = time.Duration(float64(time.Second) / politeness.RequestsPerSecond)
+	}
+	if !politeness.IgnoreCrawlDelay {
+		t.crawlDelay = crawlDelay
+	}
+	return t
+}
+
+func (t *throttle) host(host string) *hostThrottle {
+	ht, ok := t.hosts[host]
+	if !ok {
+		ht = &hostThrottle{}
+		t.hosts[host] = ht
+	}
+	return ht
+}
+
+func (t *throttle) hostInterval(host string) time.Duration {
+	interval := t.interval
+	if t.crawlDelay > interval {
+		interval = t.crawlDelay
+	}
+	return interval + t.host(host).backoff
+}
+
+func (t *throttle) nextWait(host string, now time.Time) time.Duration {
+	next := t.host(host).next
+	if now.Before(next) {
+		return next.Sub(now)
+	}
+	return 0
+}
+
+func (t *throttle) takeSlot(host string, now time.Time) {
+	t.host(host).next = now.Add(t.hostInterval(host))
+}
+
+// wait returns how long we have to wait, until we may request host again
+func (t *throttle) wait(host string, now time.Time) time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.nextWait(host, now)
+}
+
+// take a slot for a request to host
+func (t *throttle) take(host string, now time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.takeSlot(host, now)
+}
+
+// reserve the next slot for a request to host and return how long to wait for it
+func (t *throttle) reserve(host string, now time.Time) time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	wait := t.nextWait(host, now)
+	t.takeSlot(host, now.Add(wait))
+	return wait
+}
+
+// backoff of a host
+func (t *throttle) backoff(host string) time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.host(host).backoff
+}
+
+// feedback adapts the backoff for a host to the response we got
+func (t *throttle) feedback(host string, code int, duration time.Duration) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	ht := t.host(host)
+	switch true {
+	case code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable,
+		t.slowDuration > 0 && duration > t.slowDuration:
+		ht.backoff *= 2
+		if ht.backoff < backoffMin {
+			ht.backoff = backoffMin
+		}
+		if t.maxBackoff > 0 && ht.backoff > t.maxBackoff {
+			ht.backoff = t.maxBackoff
+		}
+	default:
+		ht.backoff /= 2
+		if ht.backoff < backoffThreshold {
+			ht.backoff = 0
+		}
+	}
+}
+
+// limit returns the effective requests per second for a host, 0 means unlimited
+func (t *throttle) limit(host string) float64 {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	interval := t.hostInterval(host)
+	if interval == 0 {
+		return 0
+	}
+	return float64(time.Second) / float64(interval)
+}
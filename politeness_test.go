@@ -0,0 +1,41 @@
+package walker
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/foomo/walker/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThrottle(t *testing.T) {
+	const host = "www.example.com"
+	thr := newThrottle(config.Politeness{
+		RequestsPerSecond: 4,
+		SlowDuration:      time.Second,
+		MaxBackoff:        time.Second * 3,
+	}, 0)
+	now := time.Now()
+	assert.Equal(t, time.Duration(0), thr.wait(host, now))
+	thr.take(host, now)
+	assert.Equal(t, time.Millisecond*250, thr.wait(host, now))
+	assert.Equal(t, time.Duration(0), thr.wait(host, now.Add(time.Millisecond*250)))
+	assert.Equal(t, 4.0, thr.limit(host))
+
+	// crawl delay from robots.txt wins, if it is slower
+	assert.Equal(t, 0.5, newThrottle(config.Politeness{RequestsPerSecond: 4}, time.Second*2).limit(host))
+	assert.Equal(t, 4.0, newThrottle(config.Politeness{RequestsPerSecond: 4, IgnoreCrawlDelay: true}, time.Second*2).limit(host))
+
+	// adaptive backoff
+	thr.feedback(host, http.StatusTooManyRequests, time.Millisecond)
+	assert.Equal(t, time.Second, thr.host(host).backoff)
+	thr.feedback(host, http.StatusOK, time.Second*2)
+	assert.Equal(t, time.Second*2, thr.host(host).backoff)
+	thr.feedback(host, http.StatusServiceUnavailable, time.Millisecond)
+	assert.Equal(t, time.Second*3, thr.host(host).backoff)
+	for i := 0; i < 10; i++ {
+		thr.feedback(host, http.StatusOK, time.Millisecond)
+	}
+	assert.Equal(t, time.Duration(0), thr.host(host).backoff)
+}
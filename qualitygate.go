@@ -0,0 +1,152 @@
+package walker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/foomo/walker/config"
+	"github.com/foomo/walker/vo"
+)
+
+// getBucket finds a bucket by its name or its lower boundary like 500ms
+func getBucket(key string) (bucket vo.Bucket, err error) {
+	for _, b := range vo.GetBucketList() {
+		if b.Name == key {
+			return b, nil
+		}
+	}
+	d, errParse := time.ParseDuration(key)
+	if errParse == nil {
+		for _, b := range vo.GetBucketList() {
+			if b.From == d {
+				return b, nil
+			}
+		}
+	}
+	return bucket, errors.New("unknown duration bucket: " + key)
+}
+
+// validateQualityGate fails on thresholds, that could not be evaluated after the crawl
+func validateQualityGate(gate config.QualityGate) error {
+	for key := range gate.MaxShareSlowerThan {
+		_, errBucket := getBucket(key)
+		if errBucket != nil {
+			return errBucket
+		}
+	}
+	return nil
+}
+
+// EvaluateQualityGate checks a complete status against the thresholds of a quality gate
+func EvaluateQualityGate(gate config.QualityGate, status vo.Status) (result vo.QualityGateResult, err error) {
+	result.Checks = []vo.QualityGateCheck{}
+	addCheck := func(name string, limit, value interface{}, passed bool) {
+		result.Checks = append(result.Checks, vo.QualityGateCheck{
+			Name:   name,
+			Limit:  fmt.Sprint(limit),
+			Value:  fmt.Sprint(value),
+			Passed: passed,
+		})
+	}
+	if gate.Max5xx != nil {
+		count := 0
+		for _, r := range status.Results {
+			if r.Code >= 500 {
+				count++
+			}
+		}
+		addCheck("max 5xx", *gate.Max5xx, count, count <= *gate.Max5xx)
+	}
+	if gate.MaxBrokenLinks != nil {
+		count := 0
+		for _, r := range status.Results {
+			if r.Code == http.StatusNotFound {
+				count++
+			}
+		}
+		for _, check := range status.ExternalLinks {
+			if check.Broken() {
+				count++
+			}
+		}
+		addCheck("max broken links", *gate.MaxBrokenLinks, count, count <= *gate.MaxBrokenLinks)
+	}
+	bucketKeys := make([]string, 0, len(gate.MaxShareSlowerThan))
+	for key := range gate.MaxShareSlowerThan {
+		bucketKeys = append(bucketKeys, key)
+	}
+	sort.Strings(bucketKeys)
+	for _, key := range bucketKeys {
+		bucket, errBucket := getBucket(key)
+		if errBucket != nil {
+			return result, errBucket
+		}
+		slow := 0
+		for _, r := range status.Results {
+			if r.Duration >= bucket.From {
+				slow++
+			}
+		}
+		share := 0.0
+		if len(status.Results) > 0 {
+			share = float64(slow) / float64(len(status.Results))
+		}
+		maxShare := gate.MaxShareSlowerThan[key]
+		addCheck("max share slower than "+bucket.From.String()+" ("+bucket.Name+")", maxShare, fmt.Sprintf("%.3f", share), share <= maxShare)
+	}
+	groups := make([]string, 0, len(gate.MinSchemaScore))
+	for group := range gate.MinSchemaScore {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	for _, group := range groups {
+		sum := 0
+		count := 0
+		for _, r := range status.Results {
+			if r.Group == group && r.ValidationReport != nil {
+				sum += r.ValidationReport.Score
+				count++
+			}
+		}
+		minScore := gate.MinSchemaScore[group]
+		if count == 0 {
+			addCheck("min schema score for group "+group, minScore, "no validated pages", false)
+			continue
+		}
+		average := sum / count
+		addCheck("min schema score for group "+group, minScore, average, average >= minScore)
+	}
+	for _, level := range gate.ForbiddenValidationLevels {
+		count := 0
+		for _, r := range status.Results {
+			for _, v := range r.Validations {
+				if strings.EqualFold(string(v.Level), level) {
+					count++
+				}
+			}
+		}
+		addCheck("no validations with level "+level, 0, count, count == 0)
+	}
+	return result, nil
+}
+
+// PrintQualityGate writes a pass / fail summary
+func PrintQualityGate(w io.Writer, result vo.QualityGateResult) {
+	verdict := "PASSED"
+	if !result.Passed() {
+		verdict = "FAILED"
+	}
+	headline(w, "quality gate", verdict)
+	for _, check := range result.Checks {
+		state := "pass"
+		if !check.Passed {
+			state = "FAIL"
+		}
+		fmt.Fprintln(w, state, check.Name, "limit:", check.Limit, "value:", check.Value)
+	}
+}
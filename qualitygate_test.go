@@ -0,0 +1,81 @@
+package walker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/foomo/walker/config"
+	"github.com/foomo/walker/htmlschema"
+	"github.com/foomo/walker/vo"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluateQualityGate(t *testing.T) {
+	status := vo.Status{
+		Results: map[string]vo.ScrapeResult{
+			"/":    {Code: 200, Group: "home", Duration: time.Millisecond * 50, ValidationReport: &htmlschema.Report{Score: 80}},
+			"/a":   {Code: 200, Group: "page", Duration: time.Millisecond * 600, Validations: []vo.Validation{{Level: vo.ValidationLevelWarning}}},
+			"/404": {Code: 404, Group: "page", Duration: time.Millisecond * 10},
+			"/500": {Code: 500, Group: "page", Duration: time.Millisecond * 10},
+		},
+	}
+	zero := 0
+	one := 1
+	result, errGate := EvaluateQualityGate(config.QualityGate{
+		Max5xx:         &one,
+		MaxBrokenLinks: &zero,
+		MaxShareSlowerThan: map[string]float64{
+			"500ms": 0.25,
+			"bad, users start to feel a real difference": 0.2,
+		},
+		MinSchemaScore:            map[string]int{"home": 70},
+		ForbiddenValidationLevels: []string{"error", "warning"},
+	}, status)
+	assert.NoError(t, errGate)
+	passed := map[string]bool{}
+	for _, check := range result.Checks {
+		passed[check.Name] = check.Passed
+	}
+	assert.Equal(t, map[string]bool{
+		"max 5xx":          true,
+		"max broken links": false,
+		"max share slower than 500ms (bad, users start to feel a real difference)": false,
+		"min schema score for group home":                                          true,
+		"no validations with level error":                                          true,
+		"no validations with level warning":                                        false,
+	}, passed)
+	assert.False(t, result.Passed())
+
+	_, errGate = EvaluateQualityGate(config.QualityGate{MaxShareSlowerThan: map[string]float64{"42ms": 0}}, status)
+	assert.Error(t, errGate)
+}
+
+func TestQualityGateValidation(t *testing.T) {
+	requested := false
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+	}))
+	defer testServer.Close()
+	w, errNew := New(WithRegisterer(prometheus.NewRegistry()))
+	require.NoError(t, errNew)
+	defer w.Stop()
+	// an unknown bucket fails, before anything is crawled
+	_, errWalk := w.WalkContext(context.Background(), &config.Config{
+		Target:       config.Target{BaseURL: testServer.URL, Paths: []string{"/"}},
+		IgnoreRobots: true,
+		Concurrency:  1,
+		QualityGate:  config.QualityGate{MaxShareSlowerThan: map[string]float64{"42ms": 0.1}},
+	})
+	assert.Error(t, errWalk)
+	assert.False(t, requested)
+
+	assert.NoError(t, validateQualityGate(config.QualityGate{MaxShareSlowerThan: map[string]float64{
+		"500ms": 0.25,
+		"bad, users start to feel a real difference": 0.2,
+	}}))
+}
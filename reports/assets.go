@@ -0,0 +1,66 @@
+package reports
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/foomo/walker/vo"
+)
+
+func reportAssets(status vo.Status, filter scrapeResultFilter) *Report {
+	report := newReport("assets")
+	info := report.section("assets "+strconv.Itoa(len(status.Assets))+" checked", "", LevelNone)
+	if len(status.Assets) == 0 {
+		info.note("no assets checked - is asset checking enabled?")
+		return report
+	}
+	broken := map[string][]string{}
+	oversized := map[string][]string{}
+	assetTypes := map[string]vo.AssetType{}
+	for _, res := range status.Results {
+		if filter != nil && filter(res) == false {
+			continue
+		}
+		for asset, assetType := range res.Assets {
+			check, ok := status.Assets[asset]
+			if !ok {
+				continue
+			}
+			assetTypes[asset] = assetType
+			if check.Broken() {
+				broken[asset] = append(broken[asset], res.TargetURL)
+				continue
+			}
+			if maxSize, ok := status.AssetMaxSizes[assetType]; ok && check.Length > maxSize {
+				oversized[asset] = append(oversized[asset], res.TargetURL)
+			}
+		}
+	}
+	addAssets := func(section *Section, assets map[string][]string, describe func(asset string, check vo.LinkCheck) []interface{}) {
+		keys := make([]string, 0, len(assets))
+		for asset := range assets {
+			keys = append(keys, asset)
+		}
+		sort.Strings(keys)
+		for _, asset := range keys {
+			pages := assets[asset]
+			sort.Strings(pages)
+			section.addWithChildren(asset, pages, describe(asset, status.Assets[asset])...)
+		}
+	}
+	addAssets(
+		report.section("broken assets", "assets/broken", LevelError).limitChildren(20),
+		broken,
+		func(asset string, check vo.LinkCheck) []interface{} {
+			return []interface{}{assetTypes[asset], check.Method, check.Code, check.Error}
+		},
+	)
+	addAssets(
+		report.section("oversized assets", "assets/oversized", LevelWarning).limitChildren(20),
+		oversized,
+		func(asset string, check vo.LinkCheck) []interface{} {
+			return []interface{}{assetTypes[asset], check.Length, ">", status.AssetMaxSizes[assetTypes[asset]], check.ContentType}
+		},
+	)
+	return report
+}
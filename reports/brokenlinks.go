@@ -1,16 +1,15 @@
 package reports
 
 import (
-	"io"
 	"net/http"
 	"sort"
 
 	"github.com/foomo/walker/vo"
 )
 
-func reportBrokenLinks(status vo.Status, w io.Writer, filter scrapeResultFilter) {
-	printh, println, _ := printers(w)
-	printh("broken links")
+func reportBrokenLinks(status vo.Status, filter scrapeResultFilter) *Report {
+	report := newReport("broken-links")
+	section := report.section("broken links", "broken-links", LevelError).limitChildren(20)
 	broken := map[string][]string{}
 	// collect 404s
 	for _, res := range status.Results {
@@ -18,7 +17,6 @@ func reportBrokenLinks(status vo.Status, w io.Writer, filter scrapeResultFilter)
 			continue
 		}
 		if res.Code == http.StatusNotFound {
-			//println(res.TargetURL)
 			broken[res.TargetURL] = []string{}
 		}
 	}
@@ -28,30 +26,19 @@ func reportBrokenLinks(status vo.Status, w io.Writer, filter scrapeResultFilter)
 			continue
 		}
 		for l := range res.NormalizedLinks {
-			for link := range broken {
-				if link == l {
-					broken[l] = append(broken[l], res.TargetURL)
-				}
+			if _, ok := broken[l]; ok {
+				broken[l] = append(broken[l], res.TargetURL)
 			}
 		}
 	}
-	// spit it out
-	brokenKeys := make([]string, len(broken))
-	i := 0
+	brokenKeys := make([]string, 0, len(broken))
 	for k, links := range broken {
 		sort.Strings(links)
-		brokenKeys[i] = k
-		i++
+		brokenKeys = append(brokenKeys, k)
 	}
 	sort.Strings(brokenKeys)
 	for _, brokenKey := range brokenKeys {
-		println(brokenKey, " (", len(broken[brokenKey]), "):")
-		for i, from := range broken[brokenKey] {
-			if i > 19 {
-				println("	...")
-				break
-			}
-			println("	", from)
-		}
+		section.addWithChildren(brokenKey, broken[brokenKey])
 	}
+	return report
 }
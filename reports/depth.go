@@ -0,0 +1,66 @@
+package reports
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/foomo/walker/vo"
+)
+
+// pages should not be more clicks than this away from the start pages
+const maxRecommendedHops = 3
+
+func reportDepth(status vo.Status, filter scrapeResultFilter) *Report {
+	report := newReport("depth")
+	info := report.section("click depth", "", LevelNone)
+	if status.Budget != "" {
+		info.note("the loop was ended early by its budget:", status.Budget, "-", status.BudgetDropped, "jobs were dropped")
+	}
+	// hops => pages
+	distribution := map[int][]string{}
+	inbound := map[string]int{}
+	for _, r := range status.Results {
+		for link := range r.NormalizedLinks {
+			inbound[link]++
+		}
+		if filter != nil && filter(r) == false {
+			continue
+		}
+		distribution[r.Hops] = append(distribution[r.Hops], r.TargetURL)
+	}
+	hops := make([]int, 0, len(distribution))
+	for hop := range distribution {
+		hops = append(hops, hop)
+	}
+	sort.Ints(hops)
+	for _, hop := range hops {
+		label := strconv.Itoa(hop) + " clicks"
+		if hop < 0 {
+			label = "not reached by clicks"
+		}
+		info.add(label, len(distribution[hop]), "pages")
+	}
+
+	deepSection := report.section("pages more than "+strconv.Itoa(maxRecommendedHops)+" clicks away from the start pages", "depth/deep", LevelWarning)
+	unreachedSection := report.section("pages, that were not reached by clicks", "depth/unreached", LevelNote)
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := hops[i]
+		pages := distribution[hop]
+		// the pages most linked to are the ones most likely to be important
+		sort.Slice(pages, func(a, b int) bool {
+			if inbound[pages[a]] != inbound[pages[b]] {
+				return inbound[pages[a]] > inbound[pages[b]]
+			}
+			return pages[a] < pages[b]
+		})
+		for _, page := range pages {
+			switch {
+			case hop < 0:
+				unreachedSection.add(page, inbound[page], "inbound links")
+			case hop > maxRecommendedHops:
+				deepSection.add(page, hop, "clicks", inbound[page], "inbound links")
+			}
+		}
+	}
+	return report
+}
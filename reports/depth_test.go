@@ -0,0 +1,38 @@
+package reports
+
+import (
+	"testing"
+
+	"github.com/foomo/walker/vo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportDepth(t *testing.T) {
+	page := func(targetURL string, hops int, links ...string) vo.ScrapeResult {
+		r := vo.ScrapeResult{TargetURL: targetURL, Code: 200, Hops: hops, NormalizedLinks: vo.LinkList{}}
+		for _, l := range links {
+			r.NormalizedLinks[l] = 1
+		}
+		return r
+	}
+	status := vo.Status{Results: map[string]vo.ScrapeResult{
+		"/":        page("/", 0, "/1"),
+		"/1":       page("/1", 1, "/2"),
+		"/2":       page("/2", 2, "/3"),
+		"/3":       page("/3", 3, "/4", "/5"),
+		"/4":       page("/4", 4, "/5"),
+		"/5":       page("/5", 4),
+		"/sitemap": page("/sitemap", -1),
+	}}
+	report := reportDepth(status, nil)
+	sections := map[string]*Section{}
+	for _, s := range report.Sections {
+		sections[s.Rule] = s
+	}
+	assert.Len(t, sections[""].Items, 6)
+	assert.Equal(t, "not reached by clicks", sections[""].Items[0].URL)
+	// most linked first
+	assert.Equal(t, []string{"/5", "/4"}, []string{sections["depth/deep"].Items[0].URL, sections["depth/deep"].Items[1].URL})
+	assert.Equal(t, "/sitemap", sections["depth/unreached"].Items[0].URL)
+	assert.Equal(t, 2, report.Issues())
+}
@@ -0,0 +1,151 @@
+package reports
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/foomo/walker/vo"
+)
+
+const (
+	// a group is slower, when its average duration grew more than this ...
+	diffDurationRegressionRatio = 1.2
+	// ... and at least by this
+	diffDurationRegressionMin = time.Millisecond * 100
+)
+
+func sortedKeys(m map[string]vo.ScrapeResult) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func h1(r vo.ScrapeResult) string {
+	h1s := []string{}
+	for _, heading := range r.Structure.Headings {
+		if heading.Level == 1 {
+			h1s = append(h1s, strings.TrimSpace(heading.Text))
+		}
+	}
+	return strings.Join(h1s, " | ")
+}
+
+func averageDurations(results map[string]vo.ScrapeResult, filter scrapeResultFilter) map[string]time.Duration {
+	sums := map[string]time.Duration{}
+	counts := map[string]int{}
+	for _, r := range results {
+		if filter != nil && filter(r) == false {
+			continue
+		}
+		sums[r.Group] += r.Duration
+		counts[r.Group]++
+	}
+	averages := make(map[string]time.Duration, len(sums))
+	for group, sum := range sums {
+		averages[group] = sum / time.Duration(counts[group])
+	}
+	return averages
+}
+
+func reportDiff(status vo.Status, filter scrapeResultFilter) *Report {
+	report := newReport("diff")
+	info := report.section("changes since the previous crawl", "", LevelNone)
+	previous := status.Previous
+	if previous == nil {
+		info.note("there is no previous crawl to compare to - wait for the next loop or configure a baseline")
+		return report
+	}
+	info.note(len(previous.Results), "=>", len(status.Results), "results")
+
+	newURLs := report.section("new urls", "diff/new", LevelNote)
+	removedURLs := report.section("removed urls", "diff/removed", LevelWarning)
+	codeChanges := report.section("status code changes", "diff/status", LevelWarning)
+	newBrokenLinks := report.section("new broken links", "diff/broken-links", LevelError).limitChildren(20)
+	newRedirects := report.section("new redirects", "diff/redirects", LevelWarning)
+	contentChanges := report.section("title, description and h1 changes", "diff/content", LevelNote)
+	scoreDrops := report.section("schema score drops", "diff/schema", LevelWarning)
+	durationRegressions := report.section("duration regressions per group", "diff/duration", LevelWarning)
+
+	for _, targetURL := range sortedKeys(status.Results) {
+		r := status.Results[targetURL]
+		if filter != nil && filter(r) == false {
+			continue
+		}
+		p, existed := previous.Results[targetURL]
+		if !existed {
+			newURLs.add(targetURL, r.Code)
+		}
+		if existed && p.Code != r.Code {
+			item := Item{URL: targetURL, Details: details(p.Code, "=>", r.Code)}
+			if r.Code == 0 || r.Code >= 400 {
+				item.Level = LevelError
+			}
+			codeChanges.Items = append(codeChanges.Items, item)
+		}
+		if r.Code == http.StatusNotFound && (!existed || p.Code != http.StatusNotFound) {
+			linkedFrom := []string{}
+			for _, other := range status.Results {
+				if _, ok := other.NormalizedLinks[targetURL]; ok {
+					linkedFrom = append(linkedFrom, other.TargetURL)
+				}
+			}
+			sort.Strings(linkedFrom)
+			newBrokenLinks.addWithChildren(targetURL, linkedFrom)
+		}
+		if len(r.Redirects) > 0 && (!existed || getFinalURLForScrapeResult(p) != getFinalURLForScrapeResult(r)) {
+			newRedirects.add(targetURL, "=>", getFinalURLForScrapeResult(r))
+		}
+		if !existed || p.Code != http.StatusOK || r.Code != http.StatusOK {
+			continue
+		}
+		changes := []string{}
+		if p.Structure.Title != r.Structure.Title {
+			changes = append(changes, "title: "+p.Structure.Title+" => "+r.Structure.Title)
+		}
+		if p.Structure.Description != r.Structure.Description {
+			changes = append(changes, "description: "+p.Structure.Description+" => "+r.Structure.Description)
+		}
+		if h1(p) != h1(r) {
+			changes = append(changes, "h1: "+h1(p)+" => "+h1(r))
+		}
+		if len(changes) > 0 {
+			contentChanges.addWithChildren(targetURL, changes)
+		}
+		if p.ValidationReport != nil && r.ValidationReport != nil && r.ValidationReport.Score < p.ValidationReport.Score {
+			scoreDrops.add(targetURL, p.ValidationReport.Score, "=>", r.ValidationReport.Score)
+		}
+	}
+	for _, targetURL := range sortedKeys(previous.Results) {
+		p := previous.Results[targetURL]
+		if filter != nil && filter(p) == false {
+			continue
+		}
+		if _, ok := status.Results[targetURL]; !ok {
+			removedURLs.add(targetURL, p.Code)
+		}
+	}
+
+	previousDurations := averageDurations(previous.Results, filter)
+	durations := averageDurations(status.Results, filter)
+	groups := make([]string, 0, len(durations))
+	for group := range durations {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	for _, group := range groups {
+		previousDuration, ok := previousDurations[group]
+		if !ok {
+			continue
+		}
+		duration := durations[group]
+		if float64(duration) > float64(previousDuration)*diffDurationRegressionRatio && duration-previousDuration > diffDurationRegressionMin {
+			durationRegressions.add("", "group: "+group, previousDuration, "=>", duration)
+		}
+	}
+	return report
+}
@@ -0,0 +1,52 @@
+package reports
+
+import (
+	"testing"
+	"time"
+
+	"github.com/foomo/walker/vo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportDiff(t *testing.T) {
+	page := func(targetURL string, code int, title string, duration time.Duration, links ...string) vo.ScrapeResult {
+		r := vo.ScrapeResult{
+			TargetURL:       targetURL,
+			Code:            code,
+			Group:           "default",
+			Duration:        duration,
+			NormalizedLinks: vo.LinkList{},
+			Structure:       vo.Structure{Title: title},
+		}
+		for _, l := range links {
+			r.NormalizedLinks[l] = 1
+		}
+		return r
+	}
+	previous := &vo.Status{Results: map[string]vo.ScrapeResult{
+		"http://example.com/":        page("http://example.com/", 200, "home", time.Millisecond*100, "http://example.com/a"),
+		"http://example.com/a":       page("http://example.com/a", 200, "a", time.Millisecond*100),
+		"http://example.com/removed": page("http://example.com/removed", 200, "removed", time.Millisecond*100),
+	}}
+	status := vo.Status{
+		Previous: previous,
+		Results: map[string]vo.ScrapeResult{
+			"http://example.com/":    page("http://example.com/", 200, "new home", time.Millisecond*500, "http://example.com/a", "http://example.com/new"),
+			"http://example.com/a":   page("http://example.com/a", 404, "", time.Millisecond*500),
+			"http://example.com/new": page("http://example.com/new", 200, "new", time.Millisecond*500),
+		},
+	}
+	report := reportDiff(status, nil)
+	sections := map[string]*Section{}
+	for _, s := range report.Sections {
+		sections[s.Rule] = s
+	}
+	assert.Equal(t, "http://example.com/new", sections["diff/new"].Items[0].URL)
+	assert.Equal(t, "http://example.com/removed", sections["diff/removed"].Items[0].URL)
+	assert.Equal(t, LevelError, sections["diff/status"].Items[0].Level)
+	assert.Equal(t, []string{"http://example.com/"}, sections["diff/broken-links"].Items[0].Children)
+	assert.Equal(t, []string{"title: home => new home"}, sections["diff/content"].Items[0].Children)
+	assert.Len(t, sections["diff/duration"].Items, 1)
+
+	assert.Equal(t, 0, reportDiff(vo.Status{}, nil).Issues())
+}
@@ -1,15 +1,16 @@
 package reports
 
 import (
-	"io"
+	"fmt"
 	"sort"
+	"strconv"
+	"time"
 
 	"github.com/foomo/walker/vo"
 )
 
-func reportErrors(status vo.Status, w io.Writer, filter scrapeResultFilter) {
-	printh, println, _ := printers(w)
-	printh("errors")
+func reportErrors(status vo.Status, filter scrapeResultFilter) *Report {
+	report := newReport("errors")
 	errorBuckets := map[int]map[string]vo.ScrapeResult{}
 	codes := sort.IntSlice{}
 	for _, res := range status.Results {
@@ -27,16 +28,34 @@ func reportErrors(status vo.Status, w io.Writer, filter scrapeResultFilter) {
 	}
 	sort.Sort(codes)
 	for _, code := range codes {
-		println(code, ":")
-		urls := make([]string, len(errorBuckets[code]))
-		i := 0
+		section := report.section("errors "+strconv.Itoa(code), "errors/"+strconv.Itoa(code), LevelError)
+		urls := make([]string, 0, len(errorBuckets[code]))
 		for targetURL := range errorBuckets[code] {
-			urls[i] = targetURL
-			i++
+			urls = append(urls, targetURL)
 		}
 		sort.Strings(urls)
 		for _, url := range urls {
-			println("	", url)
+			section.add(url)
 		}
 	}
+	retriedSection := report.section("retried", "errors/retried", LevelNote)
+	retried := []string{}
+	for _, res := range status.Results {
+		if filter != nil && filter(res) == false {
+			continue
+		}
+		if len(res.Attempts) > 0 {
+			retried = append(retried, res.TargetURL)
+		}
+	}
+	sort.Strings(retried)
+	for _, targetURL := range retried {
+		res := status.Results[targetURL]
+		attempts := make([]string, len(res.Attempts))
+		for i, attempt := range res.Attempts {
+			attempts[i] = fmt.Sprint(attempt.Time.Format(time.RFC3339), " ", attempt.Code, " ", attempt.Error, " ", attempt.Duration)
+		}
+		retriedSection.addWithChildren(targetURL, attempts, res.Code, "after", len(res.Attempts)+1, "attempts")
+	}
+	return report
 }
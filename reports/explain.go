@@ -0,0 +1,42 @@
+package reports
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/foomo/walker/vo"
+)
+
+func reportExplain(status vo.Status, filter scrapeResultFilter) *Report {
+	report := newReport("explain")
+	info := report.section("links "+strconv.Itoa(len(status.LinkDecisions))+" decisions", "", LevelNone)
+	if status.LinkDecisions == nil {
+		info.note("links are not explained - is explainlinks enabled?")
+		return report
+	}
+	// reason => links
+	followed := map[string][]string{}
+	notFollowed := map[string][]string{}
+	for link, decision := range status.LinkDecisions {
+		if decision.Follow {
+			followed[decision.Reason] = append(followed[decision.Reason], link)
+		} else {
+			notFollowed[decision.Reason] = append(notFollowed[decision.Reason], link)
+		}
+	}
+	addReasons := func(section *Section, reasons map[string][]string) {
+		keys := make([]string, 0, len(reasons))
+		for reason := range reasons {
+			keys = append(keys, reason)
+		}
+		sort.Strings(keys)
+		for _, reason := range keys {
+			links := reasons[reason]
+			sort.Strings(links)
+			section.addWithChildren(reason, links, len(links), "links")
+		}
+	}
+	addReasons(report.section("followed links", "", LevelNone).limitChildren(20), followed)
+	addReasons(report.section("links not followed", "", LevelNone).limitChildren(20), notFollowed)
+	return report
+}
@@ -0,0 +1,42 @@
+package reports
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/foomo/walker/vo"
+)
+
+func reportExternalLinks(status vo.Status, filter scrapeResultFilter) *Report {
+	report := newReport("external-links")
+	info := report.section("external links "+strconv.Itoa(len(status.ExternalLinks))+" checked", "", LevelNone)
+	if len(status.ExternalLinks) == 0 {
+		info.note("no external links checked - is external link checking enabled?")
+		return report
+	}
+	dead := map[string][]string{}
+	for _, res := range status.Results {
+		if filter != nil && filter(res) == false {
+			continue
+		}
+		for l := range res.NormalizedLinks {
+			check, ok := status.ExternalLinks[l]
+			if ok && check.Broken() {
+				dead[l] = append(dead[l], res.TargetURL)
+			}
+		}
+	}
+	deadKeys := make([]string, 0, len(dead))
+	for l := range dead {
+		deadKeys = append(deadKeys, l)
+	}
+	sort.Strings(deadKeys)
+	section := report.section("dead external links", "external-links/dead", LevelError).limitChildren(20)
+	for _, l := range deadKeys {
+		check := status.ExternalLinks[l]
+		pages := dead[l]
+		sort.Strings(pages)
+		section.addWithChildren(l, pages, check.Method, check.Code, check.Error)
+	}
+	return report
+}
@@ -0,0 +1,34 @@
+package reports
+
+import (
+	"sort"
+
+	"github.com/foomo/walker/vo"
+)
+
+func reportForbidden(status vo.Status, filter scrapeResultFilter) *Report {
+	report := newReport("forbidden")
+	section := report.section("references to forbidden hosts and urls", "forbidden", LevelError)
+	pages := map[string][]string{}
+	for _, r := range status.Results {
+		if filter != nil && filter(r) == false {
+			continue
+		}
+		for _, v := range r.Validations {
+			if v.Group == vo.ValidationGroupForbidden {
+				pages[r.TargetURL] = append(pages[r.TargetURL], v.Message)
+			}
+		}
+	}
+	targetURLs := make([]string, 0, len(pages))
+	for targetURL := range pages {
+		targetURLs = append(targetURLs, targetURL)
+	}
+	sort.Strings(targetURLs)
+	for _, targetURL := range targetURLs {
+		messages := pages[targetURL]
+		sort.Strings(messages)
+		section.addWithChildren(targetURL, messages)
+	}
+	return report
+}
@@ -0,0 +1,64 @@
+package reports
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/foomo/walker/vo"
+)
+
+func reportFragments(status vo.Status, filter scrapeResultFilter) *Report {
+	report := newReport("fragments")
+	anchors := map[string]map[string]bool{}
+	getAnchors := func(targetURL string) (pageAnchors map[string]bool, ok bool) {
+		if pageAnchors, ok := anchors[targetURL]; ok {
+			return pageAnchors, true
+		}
+		r, ok := status.Results[targetURL]
+		if !ok || r.Code != http.StatusOK || !strings.Contains(r.ContentType, "html") {
+			// we can not tell
+			return nil, false
+		}
+		pageAnchors = make(map[string]bool, len(r.Anchors))
+		for _, anchor := range r.Anchors {
+			pageAnchors[anchor] = true
+		}
+		anchors[targetURL] = pageAnchors
+		return pageAnchors, true
+	}
+	missing := map[string][]string{}
+	unchecked := 0
+	for _, r := range status.Results {
+		if filter != nil && filter(r) == false {
+			continue
+		}
+		for fragmentLink := range r.FragmentLinks {
+			parts := strings.SplitN(fragmentLink, "#", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			pageAnchors, ok := getAnchors(parts[0])
+			if !ok {
+				unchecked++
+				continue
+			}
+			if !pageAnchors[parts[1]] {
+				missing[fragmentLink] = append(missing[fragmentLink], r.TargetURL)
+			}
+		}
+	}
+	section := report.section("links to missing fragments", "fragments/missing", LevelError).limitChildren(20)
+	section.note(unchecked, "fragment links could not be checked, because their target was not crawled or is not a html page")
+	fragmentLinks := make([]string, 0, len(missing))
+	for fragmentLink := range missing {
+		fragmentLinks = append(fragmentLinks, fragmentLink)
+	}
+	sort.Strings(fragmentLinks)
+	for _, fragmentLink := range fragmentLinks {
+		pages := missing[fragmentLink]
+		sort.Strings(pages)
+		section.addWithChildren(fragmentLink, pages)
+	}
+	return report
+}
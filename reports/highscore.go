@@ -1,7 +1,6 @@
 package reports
 
 import (
-	"io"
 	"sort"
 	"time"
 
@@ -12,6 +11,7 @@ type score struct {
 	TargetURL string
 	Code      int
 	Duration  time.Duration
+	Timing    vo.Timing
 }
 
 type scores []score
@@ -20,24 +20,32 @@ func (s scores) Len() int           { return len(s) }
 func (s scores) Less(i, j int) bool { return s[i].Duration < s[j].Duration }
 func (s scores) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
 
-func reportHighscore(status vo.Status, w io.Writer, filter scrapeResultFilter) {
-	printh, println, _ := printers(w)
-	printh("high score")
-	scores := make(scores, len(status.Results))
-	i := 0
+func reportHighscore(status vo.Status, filter scrapeResultFilter) *Report {
+	report := newReport("highscore")
+	section := report.section("high score", "", LevelNone)
+	scores := make(scores, 0, len(status.Results))
 	for _, r := range status.Results {
 		if filter != nil && filter(r) == false {
 			continue
 		}
-		scores[i] = score{
+		scores = append(scores, score{
 			Duration:  r.Duration,
 			Code:      r.Code,
 			TargetURL: r.TargetURL,
-		}
-		i++
+			Timing:    r.Timing,
+		})
 	}
 	sort.Sort(scores)
 	for i, s := range scores {
-		println(i, s.Code, s.TargetURL, s.Duration)
+		section.add(
+			s.TargetURL, i, s.Code, s.Duration,
+			"dns", s.Timing.DNS,
+			"connect", s.Timing.Connect,
+			"tls", s.Timing.TLS,
+			"ttfb", s.Timing.TTFB,
+			"body", s.Timing.Body,
+			"total", s.Timing.Total,
+		)
 	}
+	return report
 }
@@ -0,0 +1,64 @@
+package reports
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/foomo/walker/vo"
+)
+
+func reportHistory(status vo.Status, filter scrapeResultFilter) *Report {
+	report := newReport("history")
+	crawls := report.section("crawl history "+strconv.Itoa(len(status.History))+" crawls", "", LevelNone)
+	if len(status.History) == 0 {
+		crawls.note("no history - is the history enabled?")
+		return report
+	}
+	groupMap := map[string]bool{}
+	for _, s := range status.History {
+		crawls.add(
+			"", s.Time.Format(time.RFC3339),
+			"results", s.Results,
+			"errors", s.Errors,
+			"404", s.Codes[404],
+			"5xx", count5xx(s.Codes),
+		)
+		for group := range s.Durations {
+			groupMap[group] = true
+		}
+	}
+	groups := make([]string, 0, len(groupMap))
+	for group := range groupMap {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	durations := report.section("average duration per group", "", LevelNone)
+	scores := report.section("average schema score per group", "", LevelNone)
+	for _, group := range groups {
+		durationTrend := []string{}
+		scoreTrend := []string{}
+		for _, s := range status.History {
+			if d, ok := s.Durations[group]; ok {
+				durationTrend = append(durationTrend, s.Time.Format(time.RFC3339)+" "+d.String())
+			}
+			if score, ok := s.Scores[group]; ok {
+				scoreTrend = append(scoreTrend, s.Time.Format(time.RFC3339)+" "+strconv.FormatFloat(score, 'f', 1, 64))
+			}
+		}
+		durations.addWithChildren("", durationTrend, "group: "+group)
+		if len(scoreTrend) > 0 {
+			scores.addWithChildren("", scoreTrend, "group: "+group)
+		}
+	}
+	return report
+}
+
+func count5xx(codes map[int]int) (count int) {
+	for code, c := range codes {
+		if code >= 500 {
+			count += c
+		}
+	}
+	return count
+}
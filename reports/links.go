@@ -1,33 +1,33 @@
 package reports
 
 import (
-	"io"
 	"sort"
+	"strconv"
 
 	"github.com/foomo/walker/vo"
 )
 
-func reportLinks(status vo.Status, w io.Writer, filter scrapeResultFilter) {
-	printh, println, _ := printers(w)
-	printh("links", len(status.Results))
-	for _, res := range status.Results {
+func reportLinks(status vo.Status, filter scrapeResultFilter) *Report {
+	report := newReport("links")
+	section := report.section("links "+strconv.Itoa(len(status.Results)), "", LevelNone)
+	targetURLs := make([]string, 0, len(status.Results))
+	for targetURL := range status.Results {
+		targetURLs = append(targetURLs, targetURL)
+	}
+	sort.Strings(targetURLs)
+	for _, targetURL := range targetURLs {
+		res := status.Results[targetURL]
 		if filter != nil && filter(res) == false {
 			continue
 		}
-		println(res.TargetURL)
 		links := []string{}
 		for _, r := range status.Results {
-		LinkLoop:
-			for l := range r.NormalizedLinks {
-				if l == res.TargetURL {
-					links = append(links, r.TargetURL)
-					break LinkLoop
-				}
+			if _, ok := r.NormalizedLinks[res.TargetURL]; ok {
+				links = append(links, r.TargetURL)
 			}
 		}
 		sort.Strings(links)
-		for _, l := range links {
-			println("	", l)
-		}
+		section.addWithChildren(res.TargetURL, links)
 	}
+	return report
 }
@@ -0,0 +1,130 @@
+package reports
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Level of the items in a section, sections without a level are informational
+type Level string
+
+const (
+	LevelNone    Level = ""
+	LevelError   Level = "error"
+	LevelWarning Level = "warning"
+	LevelNote    Level = "note"
+)
+
+// Item is a line in a section usually about a url
+type Item struct {
+	URL     string   `json:",omitempty"`
+	Details []string `json:",omitempty"`
+	// overrides the level of the section
+	Level Level `json:",omitempty"`
+	// urls or lines belonging to the item like the pages linking to a broken link
+	Children []string `json:",omitempty"`
+}
+
+// Section of a report
+type Section struct {
+	Title string
+	// stable identifier for machines like errors/404
+	Rule  string   `json:",omitempty"`
+	Level Level    `json:",omitempty"`
+	Notes []string `json:",omitempty"`
+	Items []Item
+	// text rendering only shows this many children, 0 shows all
+	childLimit int
+}
+
+// Report is the structured outcome of a reporter for a status
+type Report struct {
+	Name     string
+	Status   string `json:",omitempty"`
+	Sections []*Section
+	// raw data for reports, that do not fit into sections
+	Data interface{} `json:",omitempty"`
+}
+
+func newReport(name string) *Report {
+	return &Report{
+		Name:     name,
+		Sections: []*Section{},
+	}
+}
+
+func (r *Report) section(title, rule string, level Level) *Section {
+	s := &Section{
+		Title: title,
+		Rule:  rule,
+		Level: level,
+		Items: []Item{},
+	}
+	r.Sections = append(r.Sections, s)
+	return s
+}
+
+func details(a ...interface{}) []string {
+	d := make([]string, len(a))
+	for i, v := range a {
+		d[i] = fmt.Sprint(v)
+	}
+	return d
+}
+
+func (s *Section) note(a ...interface{}) {
+	s.Notes = append(s.Notes, strings.Join(details(a...), " "))
+}
+
+func (s *Section) add(url string, a ...interface{}) {
+	s.Items = append(s.Items, Item{URL: url, Details: details(a...)})
+}
+
+func (s *Section) addWithChildren(url string, children []string, a ...interface{}) {
+	s.Items = append(s.Items, Item{URL: url, Details: details(a...), Children: children})
+}
+
+func (s *Section) limitChildren(limit int) *Section {
+	s.childLimit = limit
+	return s
+}
+
+func (s *Section) itemLevel(item Item) Level {
+	if item.Level != LevelNone {
+		return item.Level
+	}
+	return s.Level
+}
+
+// message describes an item for tools, that show one line per finding
+func (s *Section) message(item Item) string {
+	message := s.Title
+	if len(item.Details) > 0 {
+		message += ": " + strings.Join(item.Details, " ")
+	}
+	return message
+}
+
+// location is where tools should point to
+func (s *Section) location(item Item) string {
+	if item.URL != "" {
+		return item.URL
+	}
+	if len(item.Children) > 0 {
+		return item.Children[0]
+	}
+	return ""
+}
+
+// Issues counts items with an error or warning level
+func (r *Report) Issues() (count int) {
+	for _, s := range r.Sections {
+		for _, item := range s.Items {
+			switch s.itemLevel(item) {
+			case LevelError, LevelWarning:
+				count++
+			}
+		}
+	}
+	return count
+}
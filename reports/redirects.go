@@ -1,16 +1,15 @@
 package reports
 
 import (
-	"io"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/foomo/walker/vo"
 )
 
-func reportRedirects(status vo.Status, w io.Writer, filter scrapeResultFilter) {
-	printh, println, _ := printers(w)
-	printh("redirects")
+func reportRedirects(status vo.Status, filter scrapeResultFilter) *Report {
+	report := newReport("redirects")
 	redirects := map[int]map[string][]string{}
 	for _, r := range status.Results {
 		if filter != nil && filter(r) == false {
@@ -38,7 +37,7 @@ func reportRedirects(status vo.Status, w io.Writer, filter scrapeResultFilter) {
 	}
 	sort.Sort(codes)
 	for _, code := range codes {
-		println(code)
+		section := report.section("redirects "+strconv.Itoa(code), "redirects/"+strconv.Itoa(code), LevelNote)
 		redirectMap := redirects[code]
 		targetURLs := []string{}
 		for targetURL := range redirectMap {
@@ -46,7 +45,8 @@ func reportRedirects(status vo.Status, w io.Writer, filter scrapeResultFilter) {
 		}
 		sort.Strings(targetURLs)
 		for _, targetURL := range targetURLs {
-			println("	", targetURL, " => ", strings.Join(redirectMap[targetURL], " => "))
+			section.add(targetURL, "=>", strings.Join(redirectMap[targetURL], " => "))
 		}
 	}
+	return report
 }
@@ -0,0 +1,330 @@
+package reports
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format of a rendered report
+type Format string
+
+const (
+	FormatText  Format = "text"
+	FormatJSON  Format = "json"
+	FormatCSV   Format = "csv"
+	FormatJUnit Format = "junit"
+	FormatSARIF Format = "sarif"
+)
+
+// Formats all supported formats
+var Formats = []Format{FormatText, FormatJSON, FormatCSV, FormatJUnit, FormatSARIF}
+
+// ParseFormat an empty format is text
+func ParseFormat(format string) (Format, error) {
+	if format == "" {
+		return FormatText, nil
+	}
+	for _, f := range Formats {
+		if string(f) == strings.ToLower(format) {
+			return f, nil
+		}
+	}
+	return "", errors.New("unknown report format: " + format)
+}
+
+// ContentType for http responses
+func (f Format) ContentType() string {
+	switch f {
+	case FormatJSON, FormatSARIF:
+		return "application/json"
+	case FormatCSV:
+		return "text/csv"
+	case FormatJUnit:
+		return "application/xml"
+	default:
+		return "text/plain; charset=utf-8"
+	}
+}
+
+// Render reports in a format
+func Render(w io.Writer, format Format, reports []*Report) error {
+	switch format {
+	case FormatText:
+		for _, r := range reports {
+			renderText(w, r)
+		}
+		return nil
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "	")
+		return encoder.Encode(reports)
+	case FormatCSV:
+		return renderCSV(w, reports)
+	case FormatJUnit:
+		return renderJUnit(w, reports)
+	case FormatSARIF:
+		return renderSARIF(w, reports)
+	}
+	return errors.New("unknown report format: " + string(format))
+}
+
+func renderText(w io.Writer, r *Report) {
+	printh, println, _ := printers(w)
+	for _, s := range r.Sections {
+		printh(s.Title)
+		for _, note := range s.Notes {
+			println(note)
+		}
+		for _, item := range s.Items {
+			line := []interface{}{}
+			if item.URL != "" {
+				line = append(line, item.URL)
+			}
+			for _, d := range item.Details {
+				line = append(line, d)
+			}
+			if len(item.Children) > 0 && s.childLimit > 0 {
+				line = append(line, "(", len(item.Children), "):")
+			}
+			println(line...)
+			for i, child := range item.Children {
+				if s.childLimit > 0 && i >= s.childLimit {
+					println("	...")
+					break
+				}
+				println("	", child)
+			}
+		}
+	}
+	if r.Data != nil {
+		yamlBytes, errYaml := yaml.Marshal(r.Data)
+		if errYaml != nil {
+			println("could not print", errYaml)
+		} else {
+			println(string(yamlBytes))
+		}
+	}
+}
+
+func renderCSV(w io.Writer, reports []*Report) error {
+	csvWriter := csv.NewWriter(w)
+	errWrite := csvWriter.Write([]string{"report", "status", "section", "rule", "level", "url", "details", "children"})
+	if errWrite != nil {
+		return errWrite
+	}
+	for _, r := range reports {
+		for _, s := range r.Sections {
+			for _, item := range s.Items {
+				errWrite := csvWriter.Write([]string{
+					r.Name,
+					r.Status,
+					s.Title,
+					s.Rule,
+					string(s.itemLevel(item)),
+					item.URL,
+					strings.Join(item.Details, " "),
+					strings.Join(item.Children, " | "),
+				})
+				if errWrite != nil {
+					return errWrite
+				}
+			}
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestSuites struct {
+	XMLName    xml.Name         `xml:"testsuites"`
+	Name       string           `xml:"name,attr"`
+	Tests      int              `xml:"tests,attr"`
+	Failures   int              `xml:"failures,attr"`
+	TestSuites []junitTestSuite `xml:"testsuite"`
+}
+
+func sectionRule(r *Report, s *Section) string {
+	if s.Rule != "" {
+		return s.Rule
+	}
+	return r.Name + "/" + strings.ReplaceAll(strings.ToLower(s.Title), " ", "-")
+}
+
+// failing sections have errors or warnings, items can have other levels than their section
+func (s *Section) failing() bool {
+	if s.Level == LevelError || s.Level == LevelWarning {
+		return true
+	}
+	for _, item := range s.Items {
+		if level := s.itemLevel(item); level == LevelError || level == LevelWarning {
+			return true
+		}
+	}
+	return false
+}
+
+// renderJUnit turns every section with errors or warnings into a test suite with a failing test per item
+func renderJUnit(w io.Writer, reports []*Report) error {
+	suites := junitTestSuites{Name: "walker"}
+	for _, r := range reports {
+		for _, s := range r.Sections {
+			if !s.failing() {
+				continue
+			}
+			name := r.Name + ": " + s.Title
+			if r.Status != "" {
+				name = r.Status + " " + name
+			}
+			suite := junitTestSuite{Name: name, TestCases: []junitTestCase{}}
+			for _, item := range s.Items {
+				testCase := junitTestCase{
+					Name:      s.location(item),
+					ClassName: sectionRule(r, s),
+				}
+				if testCase.Name == "" {
+					testCase.Name = strings.Join(item.Details, " ")
+				}
+				level := s.itemLevel(item)
+				if level == LevelError || level == LevelWarning {
+					testCase.Failure = &junitFailure{
+						Message: s.message(item),
+						Type:    string(level),
+						Text:    strings.Join(item.Children, "\n"),
+					}
+					suite.Failures++
+				}
+				suite.Tests++
+				suite.TestCases = append(suite.TestCases, testCase)
+			}
+			suites.Tests += suite.Tests
+			suites.Failures += suite.Failures
+			suites.TestSuites = append(suites.TestSuites, suite)
+		}
+	}
+	_, errWrite := io.WriteString(w, xml.Header)
+	if errWrite != nil {
+		return errWrite
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "	")
+	errEncode := encoder.Encode(suites)
+	if errEncode != nil {
+		return errEncode
+	}
+	_, errWrite = fmt.Fprintln(w)
+	return errWrite
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+	} `json:"physicalLocation"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifRun struct {
+	Tool struct {
+		Driver struct {
+			Name           string      `json:"name"`
+			InformationURI string      `json:"informationUri"`
+			Rules          []sarifRule `json:"rules"`
+		} `json:"driver"`
+	} `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+// renderSARIF reports every item of a section with a level as a result
+func renderSARIF(w io.Writer, reports []*Report) error {
+	run := sarifRun{Results: []sarifResult{}}
+	run.Tool.Driver.Name = "walker"
+	run.Tool.Driver.InformationURI = "https://github.com/foomo/walker"
+	run.Tool.Driver.Rules = []sarifRule{}
+	knownRules := map[string]bool{}
+	for _, r := range reports {
+		for _, s := range r.Sections {
+			if s.Level == LevelNone {
+				continue
+			}
+			rule := sectionRule(r, s)
+			if !knownRules[rule] {
+				knownRules[rule] = true
+				run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+					ID:               rule,
+					ShortDescription: sarifMessage{Text: s.Title},
+				})
+			}
+			for _, item := range s.Items {
+				result := sarifResult{
+					RuleID:  rule,
+					Level:   string(s.itemLevel(item)),
+					Message: sarifMessage{Text: s.message(item)},
+				}
+				if result.Level == string(LevelNone) {
+					result.Level = "none"
+				}
+				if location := s.location(item); location != "" {
+					sl := sarifLocation{}
+					sl.PhysicalLocation.ArtifactLocation.URI = location
+					result.Locations = []sarifLocation{sl}
+				}
+				run.Results = append(run.Results, result)
+			}
+		}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "	")
+	return encoder.Encode(sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{run},
+	})
+}
@@ -0,0 +1,97 @@
+package reports
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/foomo/walker/vo"
+	"github.com/stretchr/testify/assert"
+)
+
+func getTestReports() []*Report {
+	status := vo.Status{
+		Results: map[string]vo.ScrapeResult{
+			"http://example.com/": {
+				TargetURL:       "http://example.com/",
+				Code:            200,
+				NormalizedLinks: vo.LinkList{"http://example.com/gone": 1},
+			},
+			"http://example.com/gone": {
+				TargetURL: "http://example.com/gone",
+				Code:      404,
+			},
+		},
+	}
+	brokenLinks, _ := Build("broken-links", status)
+	errors, _ := Build("errors", status)
+	return []*Report{brokenLinks, errors}
+}
+
+func TestRenderText(t *testing.T) {
+	buf := &bytes.Buffer{}
+	assert.NoError(t, Render(buf, FormatText, getTestReports()))
+	assert.Contains(t, buf.String(), "http://example.com/gone ( 1 ):\n	 http://example.com/\n")
+}
+
+func TestRenderCSV(t *testing.T) {
+	buf := &bytes.Buffer{}
+	assert.NoError(t, Render(buf, FormatCSV, getTestReports()))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.Equal(t, "broken-links,,broken links,broken-links,error,http://example.com/gone,,http://example.com/", lines[1])
+}
+
+func TestRenderJUnit(t *testing.T) {
+	buf := &bytes.Buffer{}
+	assert.NoError(t, Render(buf, FormatJUnit, getTestReports()))
+	suites := junitTestSuites{}
+	assert.NoError(t, xml.Unmarshal(buf.Bytes(), &suites))
+	assert.Equal(t, 2, suites.Failures)
+	// the retried section is just a note
+	assert.Len(t, suites.TestSuites, 2)
+	assert.Equal(t, "http://example.com/gone", suites.TestSuites[0].TestCases[0].Name)
+}
+
+func TestRenderJUnitValidations(t *testing.T) {
+	validations, _ := Build("validations", vo.Status{
+		Results: map[string]vo.ScrapeResult{
+			"http://example.com/": {
+				TargetURL: "http://example.com/",
+				Code:      200,
+				Validations: []vo.Validation{
+					{Group: "forbidden", Level: vo.ValidationLevelError, Message: "links to a forbidden host"},
+					{Group: "seo", Level: vo.ValidationLevelInfo, Message: "just saying"},
+				},
+			},
+		},
+	})
+	buf := &bytes.Buffer{}
+	assert.NoError(t, Render(buf, FormatJUnit, []*Report{validations}))
+	suites := junitTestSuites{}
+	assert.NoError(t, xml.Unmarshal(buf.Bytes(), &suites))
+	assert.Len(t, suites.TestSuites, 1)
+	assert.Equal(t, 2, suites.Tests)
+	assert.Equal(t, 1, suites.Failures)
+}
+
+func TestRenderSARIF(t *testing.T) {
+	buf := &bytes.Buffer{}
+	assert.NoError(t, Render(buf, FormatSARIF, getTestReports()))
+	log := sarifLog{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &log))
+	assert.Equal(t, "2.1.0", log.Version)
+	assert.Len(t, log.Runs[0].Results, 2)
+	assert.Equal(t, "errors/404", log.Runs[0].Results[1].RuleID)
+	assert.Equal(t, "http://example.com/gone", log.Runs[0].Results[1].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+}
+
+func TestParseFormat(t *testing.T) {
+	format, errFormat := ParseFormat("")
+	assert.NoError(t, errFormat)
+	assert.Equal(t, FormatText, format)
+	_, errFormat = ParseFormat("pdf")
+	assert.Error(t, errFormat)
+}
@@ -1,6 +1,7 @@
 package reports
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"math"
@@ -14,7 +15,7 @@ import (
 )
 
 type scrapeResultFilter func(res vo.ScrapeResult) bool
-type reporter func(status vo.Status, w io.Writer, filter scrapeResultFilter)
+type reporter func(status vo.Status, filter scrapeResultFilter) *Report
 
 func GetReportHandlerMenuHTML(basePath string) string {
 	return `
@@ -29,8 +30,19 @@ func GetReportHandlerMenuHTML(basePath string) string {
 		<li><a href="` + basePath + `/redirects">redirects</a></li>
 		<li><a href="` + basePath + `/schema">schema</a></li>
 		<li><a href="` + basePath + `/validations">validations</a></li>
+		<li><a href="` + basePath + `/diff?status=complete">diff - what changed since the previous crawl</a></li>
+		<li><a href="` + basePath + `/history?status=complete">history - trends of the last crawls</a></li>
 		<li><a href="` + basePath + `/errors">errors - calls that returned error status codes</a></li>
 		<li><a href="` + basePath + `/links">links where are pages being linked from</a></li>
+		<li><a href="` + basePath + `/external-links">dead external links and the pages linking to them</a></li>
+		<li><a href="` + basePath + `/fragments">links to fragments / anchors, that do not exist on the target page</a></li>
+		<li><a href="` + basePath + `/assets">broken and oversized assets like images, scripts, stylesheets and fonts</a></li>
+		<li><a href="` + basePath + `/forbidden">references to forbidden hosts like stage systems</a></li>
+		<li><a href="` + basePath + `/sitemap">sitemap coverage - orphan and unlisted pages, broken sitemap entries</a></li>
+		<li><a href="` + basePath + `/variants">variants - pages, that are linked with different spellings like /a, /a/ and /A</a></li>
+		<li><a href="` + basePath + `/explain">explain - why links were followed or not (needs explainlinks)</a></li>
+		<li><a href="` + basePath + `/depth">depth - click depth distribution and pages buried too deep</a></li>
+		<li><a href="` + basePath + `/tls">tls - certificate problems, expiring certificates and outdated protocol versions</a></li>
 	</ul>
 	<p>query parameters</p>
 	<table>
@@ -54,6 +66,11 @@ func GetReportHandlerMenuHTML(basePath string) string {
 			<td>filter all urls with given prefix</td>
 			<td>?prefix=http...</td>
 		</tr>
+		<tr>
+			<td>format</td>
+			<td>text, json, csv, junit or sarif</td>
+			<td>?format=junit</td>
+		</tr>
 	</table>
 	`
 }
@@ -68,35 +85,17 @@ func GetReportHandler(basePath string) func(
 	) {
 		path := strings.TrimPrefix(r.URL.Path, basePath+"/")
 		fmt.Println("handling reports:", path)
-		var rep reporter
-		var f scrapeResultFilter
-		switch true {
-		case strings.HasPrefix(path, "seo"):
-			rep = reportSEO
-		case strings.HasPrefix(path, "broken-links"):
-			rep = reportBrokenLinks
-		case strings.HasPrefix(path, "results"):
-			rep = reportResults
-		case strings.HasPrefix(path, "list"):
-			rep = reportList
-		case strings.HasPrefix(path, "highscore"):
-			rep = reportHighscore
-		case strings.HasPrefix(path, "summary"):
-			rep = reportSummary
-		case strings.HasPrefix(path, "errors"):
-			rep = reportErrors
-		case strings.HasPrefix(path, "validations"):
-			rep = reportValidations
-		case strings.HasPrefix(path, "schema"):
-			rep = reportSchema
-		case strings.HasPrefix(path, "redirects"):
-			rep = reportRedirects
-		case strings.HasPrefix(path, "links"):
-			rep = reportLinks
-		default:
+		rep, ok := getReporter(path)
+		if !ok {
 			http.NotFound(w, r)
 			return
 		}
+		format, errFormat := ParseFormat(r.URL.Query().Get("format"))
+		if errFormat != nil {
+			http.Error(w, errFormat.Error(), http.StatusBadRequest)
+			return
+		}
+		var f scrapeResultFilter
 		url := r.URL.Query().Get("url")
 		if url != "" {
 			f = func(res vo.ScrapeResult) bool {
@@ -122,19 +121,96 @@ func GetReportHandler(basePath string) func(
 		if len(statuses) == 0 {
 			statuses = []string{statusRunning, statusComplete}
 		}
-		report(rep, w, f, statuses, completeStatus, runningStatus)
+		w.Header().Set("Content-Type", format.ContentType())
+		errRender := report(rep, w, format, f, statuses, completeStatus, runningStatus)
+		if errRender != nil {
+			fmt.Println("could not render report", errRender)
+		}
+	}
+}
+
+func getReporter(path string) (rep reporter, ok bool) {
+	switch true {
+	case strings.HasPrefix(path, "seo"):
+		return reportSEO, true
+	case strings.HasPrefix(path, "broken-links"):
+		return reportBrokenLinks, true
+	case strings.HasPrefix(path, "results"):
+		return reportResults, true
+	case strings.HasPrefix(path, "list"):
+		return reportList, true
+	case strings.HasPrefix(path, "highscore"):
+		return reportHighscore, true
+	case strings.HasPrefix(path, "summary"):
+		return reportSummary, true
+	case strings.HasPrefix(path, "diff"):
+		return reportDiff, true
+	case strings.HasPrefix(path, "history"):
+		return reportHistory, true
+	case strings.HasPrefix(path, "errors"):
+		return reportErrors, true
+	case strings.HasPrefix(path, "validations"):
+		return reportValidations, true
+	case strings.HasPrefix(path, "schema"):
+		return reportSchema, true
+	case strings.HasPrefix(path, "redirects"):
+		return reportRedirects, true
+	case strings.HasPrefix(path, "links"):
+		return reportLinks, true
+	case strings.HasPrefix(path, "external-links"):
+		return reportExternalLinks, true
+	case strings.HasPrefix(path, "fragments"):
+		return reportFragments, true
+	case strings.HasPrefix(path, "assets"):
+		return reportAssets, true
+	case strings.HasPrefix(path, "forbidden"):
+		return reportForbidden, true
+	case strings.HasPrefix(path, "sitemap"):
+		return reportSitemap, true
+	case strings.HasPrefix(path, "variants"):
+		return reportVariants, true
+	case strings.HasPrefix(path, "explain"):
+		return reportExplain, true
+	case strings.HasPrefix(path, "depth"):
+		return reportDepth, true
+	case strings.HasPrefix(path, "tls"):
+		return reportTLS, true
+	default:
+		return nil, false
 	}
 }
 
+// Names of all reports
+var Names = []string{
+	"seo", "broken-links", "results", "list", "highscore", "summary", "errors", "validations", "schema",
+	"redirects", "links", "diff", "history", "external-links", "fragments", "assets", "forbidden", "sitemap", "tls", "variants", "explain", "depth",
+}
+
+// DefaultNames are the reports, that tell about problems
+var DefaultNames = []string{
+	"errors", "broken-links", "seo", "redirects", "validations", "schema",
+	"external-links", "fragments", "assets", "forbidden", "sitemap", "tls", "variants", "depth", "diff",
+}
+
+// Build the report with the given name for a status
+func Build(name string, status vo.Status) (*Report, error) {
+	rep, ok := getReporter(name)
+	if !ok {
+		return nil, errors.New("unknown report: " + name)
+	}
+	return rep(status, nil), nil
+}
+
 const (
 	statusRunning  string = "running"
 	statusComplete string = "complete"
 )
 
 func report(
-	r reporter, w io.Writer, filter scrapeResultFilter,
+	r reporter, w io.Writer, format Format, filter scrapeResultFilter,
 	statuses []string, completeStatus, runningStatus *vo.Status,
-) {
+) error {
+	reports := []*Report{}
 	_, println, _ := printers(w)
 	for _, statusName := range statuses {
 		var status *vo.Status
@@ -144,16 +220,28 @@ func report(
 		case statusComplete:
 			status = completeStatus
 		}
-		if status != nil {
+		if status == nil {
+			if format == FormatText {
+				println("STATUS", statusName, "is nil")
+			}
+			continue
+		}
+		rep := r(*status, filter)
+		rep.Status = statusName
+		if format == FormatText {
 			println("STATUS", statusName)
 			println("=============================================================================")
-			r(*status, w, filter)
+			renderText(w, rep)
 			println()
 			println()
-		} else {
-			println("STATUS", statusName, "is nil")
+			continue
 		}
+		reports = append(reports, rep)
+	}
+	if format == FormatText {
+		return nil
 	}
+	return Render(w, format, reports)
 }
 
 func printers(w io.Writer) (printh func(header ...interface{}), println func(a ...interface{}), printsep func()) {
@@ -183,23 +271,18 @@ func (d duplications) add(value, url string) {
 	d[value] = append(d[value], url)
 }
 
-func (d duplications) printlnDuplications(w io.Writer) {
-	_, println, _ := printers(w)
-	values := make([]string, len(d))
-	i := 0
+// addToSection adds values, that are used by more than one url
+func (d duplications) addToSection(section *Section) {
+	values := make([]string, 0, len(d))
 	for value := range d {
-		values[i] = value
-		i++
+		values = append(values, value)
 	}
 	sort.Strings(values)
 	for _, value := range values {
 		urls := d[value]
 		sort.Strings(urls)
 		if len(urls) > 1 {
-			println(value)
-			for _, url := range urls {
-				println("	", url)
-			}
+			section.addWithChildren("", urls, value)
 		}
 	}
 }
@@ -223,23 +306,21 @@ func getFinalURLForScrapeResult(r vo.ScrapeResult) string {
 	return finalURL
 }
 
-func reportList(status vo.Status, w io.Writer, filter scrapeResultFilter) {
-	printh, println, _ := printers(w)
-	printh("results", len(status.Results))
-	results := make([]string, len(status.Results))
-	i := 0
+func reportList(status vo.Status, filter scrapeResultFilter) *Report {
+	report := newReport("list")
+	resultSection := report.section("results "+strconv.Itoa(len(status.Results)), "", LevelNone)
+	results := make([]string, 0, len(status.Results))
 	for _, res := range status.Results {
 		if filter != nil && filter(res) == false {
 			continue
 		}
-		results[i] = strconv.Itoa(res.Code) + " " + res.TargetURL
-		i++
+		results = append(results, res.TargetURL)
 	}
 	sort.Strings(results)
-	for i, r := range results {
-		println(i, r)
+	for _, targetURL := range results {
+		resultSection.add(targetURL, status.Results[targetURL].Code)
 	}
-	printh("open jobs")
+	jobSection := report.section("open jobs", "", LevelNone)
 	jobs := []string{}
 	for url, done := range status.Jobs {
 		if !done {
@@ -247,20 +328,28 @@ func reportList(status vo.Status, w io.Writer, filter scrapeResultFilter) {
 		}
 	}
 	sort.Strings(jobs)
-	for i, url := range jobs {
-		println(i, url)
+	for _, url := range jobs {
+		jobSection.add(url)
 	}
+	return report
 }
 
-func reportSummary(status vo.Status, w io.Writer, filter scrapeResultFilter) {
-	printh, _, _ := printers(w)
-	printh("summary")
-	ReportSummaryBody(status, w, filter)
+func reportSummary(status vo.Status, filter scrapeResultFilter) *Report {
+	report := newReport("summary")
+	report.section("summary", "", LevelNone)
+	addSummarySections(report, status, filter)
+	return report
 }
 
+// ReportSummaryBody writes status codes, performance buckets and request phases
 func ReportSummaryBody(status vo.Status, w io.Writer, filter scrapeResultFilter) {
-	printh, println, _ := printers(w)
-	printh("status codes")
+	report := newReport("summary")
+	addSummarySections(report, status, filter)
+	renderText(w, report)
+}
+
+func addSummarySections(report *Report, status vo.Status, filter scrapeResultFilter) {
+	statusSection := report.section("status codes", "", LevelNone)
 	statusMap := map[int]int{}
 	for _, r := range status.Results {
 		if filter != nil && filter(r) == false {
@@ -274,14 +363,14 @@ func ReportSummaryBody(status vo.Status, w io.Writer, filter scrapeResultFilter)
 	}
 	sort.Sort(codes)
 	for _, code := range codes {
-		println(code, statusMap[code])
+		statusSection.add("", code, statusMap[code])
 	}
-	printh("performance buckets")
-	groupedBucketListStatus(w, status.Results)
+	groupedBucketListStatus(report.section("performance buckets", "", LevelNone), status.Results)
+	groupedTimings(report.section("request phases", "", LevelNone), status.Results, filter)
 }
 
 func groupedBucketListStatus(
-	writer io.Writer,
+	section *Section,
 	results map[string]vo.ScrapeResult,
 ) {
 	max := int64(0)
@@ -290,16 +379,13 @@ func groupedBucketListStatus(
 	for _, r := range results {
 		groups[r.Group]++
 	}
-	i := 0
-	groupNames := make([]string, len(groups))
-
+	groupNames := make([]string, 0, len(groups))
 	for group := range groups {
-		groupNames[i] = group
-		i++
+		groupNames = append(groupNames, group)
 	}
 	sort.Strings(groupNames)
 	for _, groupName := range groupNames {
-		fmt.Fprintln(writer, "group: "+groupName)
+		section.add("", "group: "+groupName)
 		for _, bucket := range vo.GetBucketList() {
 			bucketI := 0
 			for _, result := range results {
@@ -316,8 +402,8 @@ func groupedBucketListStatus(
 					}
 				}
 			}
-			fmt.Fprintln(
-				writer,
+			section.add(
+				"",
 				bucketI,
 				"	",
 				math.Round(float64(bucketI)/float64(groups[groupName])*100),
@@ -326,5 +412,44 @@ func groupedBucketListStatus(
 			)
 		}
 	}
-	fmt.Fprintln(writer, "=>", min, time.Unix(min, 0), max, time.Unix(max, 0))
+	section.note("=>", min, time.Unix(min, 0), max, time.Unix(max, 0))
+}
+
+// groupedTimings adds the average and 90th percentile of all request phases per group
+func groupedTimings(
+	section *Section,
+	results map[string]vo.ScrapeResult,
+	filter scrapeResultFilter,
+) {
+	groups := map[string][]vo.Timing{}
+	for _, r := range results {
+		if filter != nil && filter(r) == false {
+			continue
+		}
+		groups[r.Group] = append(groups[r.Group], r.Timing)
+	}
+	groupNames := make([]string, 0, len(groups))
+	for group := range groups {
+		groupNames = append(groupNames, group)
+	}
+	sort.Strings(groupNames)
+	for _, groupName := range groupNames {
+		timings := groups[groupName]
+		section.add("", "group: "+groupName, "(", len(timings), ")")
+		for _, phase := range vo.TimingPhases {
+			durations := make([]time.Duration, len(timings))
+			sum := time.Duration(0)
+			for i, timing := range timings {
+				durations[i] = timing.Phases()[phase]
+				sum += durations[i]
+			}
+			sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+			section.add(
+				"",
+				"	", phase,
+				"	avg", sum/time.Duration(len(durations)),
+				"	p90", durations[int(float64(len(durations)-1)*0.9)],
+			)
+		}
+	}
 }
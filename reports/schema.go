@@ -1,27 +1,50 @@
 package reports
 
 import (
-	"io"
+	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/foomo/walker/vo"
 )
 
-func reportSchema(status vo.Status, w io.Writer, filter scrapeResultFilter) {
-	printh, println, _ := printers(w)
-	if filter == nil {
-		printh("results", len(status.Results))
-	} else {
-		printh("filtered results")
+func reportSchema(status vo.Status, filter scrapeResultFilter) *Report {
+	report := newReport("schema")
+	section := report.section("schema validations", "schema", LevelWarning)
+	missing := report.section("no validation report", "", LevelNone)
+	targetURLs := make([]string, 0, len(status.Results))
+	for targetURL := range status.Results {
+		targetURLs = append(targetURLs, targetURL)
 	}
-	for _, res := range status.Results {
+	sort.Strings(targetURLs)
+	for _, targetURL := range targetURLs {
+		res := status.Results[targetURL]
 		if filter != nil && filter(res) == false {
 			continue
 		}
 		if res.ValidationReport == nil {
-			println("no validation report for:", res.TargetURL)
+			missing.add(res.TargetURL)
 			continue
 		}
-		println("validation report for:", res.TargetURL)
-		res.ValidationReport.Print(w)
+		validations := []string{}
+		for _, v := range res.ValidationReport.Validations {
+			attrs := []string{}
+			if v.Element != nil {
+				for _, attr := range v.Element.Attributes {
+					attrs = append(attrs, attr.Name+":"+attr.Value)
+				}
+			}
+			validations = append(validations, fmt.Sprint(v.Path, " ", strings.Join(attrs, ","), " ", v.Type, ": ", v.Comment, " penalty: ", v.Penalty))
+		}
+		item := Item{
+			URL:      res.TargetURL,
+			Details:  details("score", res.ValidationReport.Score),
+			Children: validations,
+		}
+		if len(validations) == 0 {
+			item.Level = LevelNote
+		}
+		section.Items = append(section.Items, item)
 	}
+	return report
 }
@@ -2,7 +2,6 @@ package reports
 
 import (
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"sort"
@@ -36,8 +35,8 @@ func normalizeCanonical(target, canonical string) string {
 	return normalized
 }
 
-func reportSEO(status vo.Status, w io.Writer, filter scrapeResultFilter) {
-	printh, println, _ := printers(w)
+func reportSEO(status vo.Status, filter scrapeResultFilter) *Report {
+	report := newReport("seo")
 	h1s := duplications{}
 	titles := duplications{}
 	descriptions := duplications{}
@@ -45,20 +44,17 @@ func reportSEO(status vo.Status, w io.Writer, filter scrapeResultFilter) {
 	missingH1 := uniqueList{}
 	emptyH1 := uniqueList{}
 	missingDescriptions := uniqueList{}
-	printh("SEO duplications")
 	for _, r := range status.Results {
 		if filter != nil && filter(r) == false {
 			continue
 		}
 		if r.Code != http.StatusOK {
-			// println("skipping with code", r.Code, r.TargetURL)
 			continue
 		}
 		finalURL := getFinalURLForScrapeResult(r)
 		normalizedCanonical := normalizeCanonical(r.TargetURL, r.Structure.Canonical)
 		if normalizedCanonical != finalURL {
 			// we are skipping this one
-			// println("skipping normalizedCanonical != finalURL", normalizedCanonical, "!=", finalURL)
 			continue
 		}
 		if strings.Contains(r.ContentType, "html") {
@@ -90,27 +86,23 @@ func reportSEO(status vo.Status, w io.Writer, filter scrapeResultFilter) {
 			fmt.Println(r.ContentType)
 		}
 	}
-	printDuplicates := func(title string, d duplications) {
-		if len(d) > 0 {
-			printh(title)
-			d.printlnDuplications(w)
-		}
+	addDuplicates := func(title, rule string, d duplications) {
+		d.addToSection(report.section(title, rule, LevelWarning))
 	}
-	printDuplicates("duplicate h1", h1s)
-	printDuplicates("duplicate titles", titles)
-	printDuplicates("duplicate descriptions", descriptions)
+	addDuplicates("duplicate h1", "seo/duplicate-h1", h1s)
+	addDuplicates("duplicate titles", "seo/duplicate-title", titles)
+	addDuplicates("duplicate descriptions", "seo/duplicate-description", descriptions)
 
-	printList := func(name string, list []string) {
-		if len(list) > 0 {
-			printh(name)
-			sort.Strings(list)
-			for _, l := range list {
-				println("	", l)
-			}
+	addList := func(title, rule string, list []string) {
+		section := report.section(title, rule, LevelWarning)
+		sort.Strings(list)
+		for _, l := range list {
+			section.add(l)
 		}
 	}
-	printList("missing titles", missingTitles)
-	printList("missing descriptions", missingDescriptions)
-	printList("missing h1", missingH1)
-	printList("empty h1", emptyH1)
+	addList("missing titles", "seo/missing-title", missingTitles)
+	addList("missing descriptions", "seo/missing-description", missingDescriptions)
+	addList("missing h1", "seo/missing-h1", missingH1)
+	addList("empty h1", "seo/empty-h1", emptyH1)
+	return report
 }
@@ -0,0 +1,94 @@
+package reports
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/foomo/walker/vo"
+)
+
+func reportSitemap(status vo.Status, filter scrapeResultFilter) *Report {
+	report := newReport("sitemap")
+	info := report.section("sitemap coverage "+strconv.Itoa(len(status.Sitemap))+" sitemap entries", "", LevelNone)
+	if len(status.Sitemap) == 0 {
+		info.note("no sitemap entries - is sitemap seeding enabled?")
+		return report
+	}
+	// results and sitemap entries are keyed by url, the lists need no dedup and are sorted once in addList
+	orphans := []string{}
+	unlisted := []string{}
+	for _, r := range status.Results {
+		if filter != nil && filter(r) == false {
+			continue
+		}
+		switch r.Discovery {
+		case vo.DiscoverySitemap:
+			orphans = append(orphans, r.TargetURL)
+		case vo.DiscoveryLinks:
+			// only canonical html pages belong into a sitemap
+			if r.Code == http.StatusOK &&
+				strings.Contains(r.ContentType, "html") &&
+				!strings.Contains(r.Structure.Robots, "noindex") &&
+				(r.Structure.Canonical == "" || normalizeCanonical(r.TargetURL, r.Structure.Canonical) == r.TargetURL) {
+				unlisted = append(unlisted, r.TargetURL)
+			}
+		}
+	}
+
+	notCrawled := []string{}
+	badStatus := map[string]int{}
+	nonCanonical := map[string]string{}
+	for loc := range status.Sitemap {
+		r, ok := status.Results[loc]
+		if !ok {
+			notCrawled = append(notCrawled, loc)
+			continue
+		}
+		if filter != nil && filter(r) == false {
+			continue
+		}
+		if r.Code != http.StatusOK {
+			badStatus[loc] = r.Code
+			continue
+		}
+		normalizedCanonical := normalizeCanonical(r.TargetURL, r.Structure.Canonical)
+		if normalizedCanonical != "" && normalizedCanonical != loc {
+			nonCanonical[loc] = normalizedCanonical
+		}
+	}
+
+	addList := func(title, rule string, level Level, list []string) {
+		section := report.section(title, rule, level)
+		sort.Strings(list)
+		for _, l := range list {
+			section.add(l)
+		}
+	}
+	addList("orphan pages - in sitemap, but never linked", "sitemap/orphan", LevelWarning, orphans)
+	addList("unlisted pages - linked, but missing in sitemap", "sitemap/unlisted", LevelWarning, unlisted)
+
+	badStatusSection := report.section("sitemap entries without status 200", "sitemap/status", LevelError)
+	locs := make([]string, 0, len(badStatus))
+	for loc := range badStatus {
+		locs = append(locs, loc)
+	}
+	sort.Strings(locs)
+	for _, loc := range locs {
+		badStatusSection.add(loc, badStatus[loc], "in", status.Sitemap[loc].Sitemap)
+	}
+
+	nonCanonicalSection := report.section("non canonical sitemap entries", "sitemap/non-canonical", LevelWarning)
+	locs = make([]string, 0, len(nonCanonical))
+	for loc := range nonCanonical {
+		locs = append(locs, loc)
+	}
+	sort.Strings(locs)
+	for _, loc := range locs {
+		nonCanonicalSection.add(loc, "=>", nonCanonical[loc])
+	}
+
+	addList("sitemap entries, that were not crawled (ignored, robots or still open)", "sitemap/not-crawled", LevelNote, notCrawled)
+	return report
+}
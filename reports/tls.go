@@ -0,0 +1,115 @@
+package reports
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/foomo/walker/vo"
+)
+
+// how early we start to complain about expiring certificates
+const tlsExpiryWarning = time.Hour * 24 * 14
+
+func reportTLS(status vo.Status, filter scrapeResultFilter) *Report {
+	report := newReport("tls")
+	// host => problem => pages
+	problems := map[string]map[string][]string{}
+	expiring := map[string][]string{}
+	outdated := map[string][]string{}
+	certificates := map[string]vo.TLSInfo{}
+	for _, r := range status.Results {
+		if filter != nil && filter(r) == false {
+			continue
+		}
+		if r.TLS == nil {
+			continue
+		}
+		host := r.TargetURL
+		if u, errParse := url.Parse(r.TargetURL); errParse == nil {
+			host = u.Host
+		}
+		for _, problem := range r.TLS.Problems {
+			if problems[host] == nil {
+				problems[host] = map[string][]string{}
+			}
+			problems[host][problem] = append(problems[host][problem], r.TargetURL)
+		}
+		if r.TLS.NotAfter.IsZero() {
+			continue
+		}
+		certificates[host] = *r.TLS
+		if r.TLS.NotAfter.Sub(r.Time) < tlsExpiryWarning {
+			expiring[host] = append(expiring[host], r.TargetURL)
+		}
+		if r.TLS.Version == "TLS 1.0" || r.TLS.Version == "TLS 1.1" {
+			outdated[host] = append(outdated[host], r.TargetURL)
+		}
+	}
+
+	hosts := make([]string, 0, len(certificates)+len(problems))
+	for host := range certificates {
+		hosts = append(hosts, host)
+	}
+	for host := range problems {
+		if _, ok := certificates[host]; !ok {
+			hosts = append(hosts, host)
+		}
+	}
+	sort.Strings(hosts)
+
+	problemSection := report.section("certificate problems", "tls/problem", LevelError).limitChildren(20)
+	for _, host := range hosts {
+		if _, ok := problems[host]; !ok {
+			continue
+		}
+		hostProblems := make([]string, 0, len(problems[host]))
+		for problem := range problems[host] {
+			hostProblems = append(hostProblems, problem)
+		}
+		sort.Strings(hostProblems)
+		for _, problem := range hostProblems {
+			pages := problems[host][problem]
+			sort.Strings(pages)
+			problemSection.addWithChildren(host, pages, problem)
+		}
+	}
+	expiringSection := report.section("certificates expiring within "+tlsExpiryWarning.String(), "tls/expiring", LevelWarning).limitChildren(20)
+	for _, host := range hosts {
+		pages, ok := expiring[host]
+		if !ok {
+			continue
+		}
+		sort.Strings(pages)
+		expiringSection.addWithChildren(host, pages, "expires", certificates[host].NotAfter.Format(time.RFC3339))
+	}
+	outdatedSection := report.section("outdated protocol versions", "tls/version", LevelWarning).limitChildren(20)
+	for _, host := range hosts {
+		pages, ok := outdated[host]
+		if !ok {
+			continue
+		}
+		sort.Strings(pages)
+		outdatedSection.addWithChildren(host, pages, certificates[host].Version)
+	}
+	certificateSection := report.section("certificates", "", LevelNone)
+	if len(hosts) == 0 {
+		certificateSection.note("no https results")
+	}
+	for _, host := range hosts {
+		certificate, ok := certificates[host]
+		if !ok {
+			continue
+		}
+		certificateSection.add(
+			host,
+			certificate.Version,
+			"subject", certificate.Subject,
+			"issuer", certificate.Issuer,
+			"names", strings.Join(certificate.DNSNames, ","),
+			"expires", certificate.NotAfter.Format(time.RFC3339),
+		)
+	}
+	return report
+}
@@ -0,0 +1,46 @@
+package reports
+
+import (
+	"testing"
+	"time"
+
+	"github.com/foomo/walker/vo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportTLS(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	page := func(targetURL string, info *vo.TLSInfo) vo.ScrapeResult {
+		return vo.ScrapeResult{
+			TargetURL: targetURL,
+			Code:      200,
+			Time:      now,
+			TLS:       info,
+		}
+	}
+	fine := &vo.TLSInfo{Version: "TLS 1.3", Subject: "fine.example.com", NotAfter: now.Add(time.Hour * 24 * 90)}
+	expiring := &vo.TLSInfo{Version: "TLS 1.1", Subject: "old.example.com", NotAfter: now.Add(time.Hour * 24)}
+	untrusted := &vo.TLSInfo{Version: "TLS 1.2", Subject: "self.example.com", NotAfter: now.Add(time.Hour * 24 * 90), Problems: []string{"x509: certificate signed by unknown authority"}}
+	status := vo.Status{
+		Results: map[string]vo.ScrapeResult{
+			"https://fine.example.com/": page("https://fine.example.com/", fine),
+			"https://old.example.com/":  page("https://old.example.com/", expiring),
+			"https://old.example.com/a": page("https://old.example.com/a", expiring),
+			"https://self.example.com/": page("https://self.example.com/", untrusted),
+			"http://plain.example.com/": page("http://plain.example.com/", nil),
+		},
+	}
+	report := reportTLS(status, nil)
+	sections := map[string]*Section{}
+	for _, s := range report.Sections {
+		sections[s.Rule] = s
+	}
+	assert.Len(t, sections["tls/problem"].Items, 1)
+	assert.Equal(t, "self.example.com", sections["tls/problem"].Items[0].URL)
+	assert.Len(t, sections["tls/expiring"].Items, 1)
+	assert.Equal(t, []string{"https://old.example.com/", "https://old.example.com/a"}, sections["tls/expiring"].Items[0].Children)
+	assert.Len(t, sections["tls/version"].Items, 1)
+	assert.Equal(t, 3, report.Issues())
+
+	assert.Equal(t, 0, reportTLS(vo.Status{}, nil).Issues())
+}
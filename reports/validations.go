@@ -1,23 +1,42 @@
 package reports
 
 import (
-	"io"
+	"sort"
 
 	"github.com/foomo/walker/vo"
 )
 
-func reportValidations(status vo.Status, w io.Writer, filter scrapeResultFilter) {
-	printh, println, _ := printers(w)
-	printh("validations")
-	for _, r := range status.Results {
+func validationLevel(level vo.ValidationLevel) Level {
+	switch level {
+	case vo.ValidationLevelError:
+		return LevelError
+	case vo.ValidationLevelWarning:
+		return LevelWarning
+	default:
+		return LevelNote
+	}
+}
+
+func reportValidations(status vo.Status, filter scrapeResultFilter) *Report {
+	report := newReport("validations")
+	section := report.section("validations", "validations", LevelNote)
+	targetURLs := make([]string, 0, len(status.Results))
+	for targetURL := range status.Results {
+		targetURLs = append(targetURLs, targetURL)
+	}
+	sort.Strings(targetURLs)
+	for _, targetURL := range targetURLs {
+		r := status.Results[targetURL]
 		if filter != nil && filter(r) == false {
 			continue
 		}
-		if len(r.Validations) > 0 {
-			println(r.TargetURL)
-			for _, v := range r.Validations {
-				println("	", v.Group, v.Level, v.Message)
-			}
+		for _, v := range r.Validations {
+			section.Items = append(section.Items, Item{
+				URL:     r.TargetURL,
+				Details: details(v.Group, v.Level, v.Message),
+				Level:   validationLevel(v.Level),
+			})
 		}
 	}
+	return report
 }
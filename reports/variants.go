@@ -0,0 +1,31 @@
+package reports
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/foomo/walker/vo"
+)
+
+func reportVariants(status vo.Status, filter scrapeResultFilter) *Report {
+	report := newReport("variants")
+	section := report.section("inconsistent links - pages linked with different spellings", "variants/inconsistent", LevelWarning).limitChildren(20)
+	normalizedLinks := make([]string, 0, len(status.LinkVariants))
+	for normalizedLink := range status.LinkVariants {
+		if r, ok := status.Results[normalizedLink]; ok && filter != nil && filter(r) == false {
+			continue
+		}
+		normalizedLinks = append(normalizedLinks, normalizedLink)
+	}
+	sort.Strings(normalizedLinks)
+	for _, normalizedLink := range normalizedLinks {
+		spellings := status.LinkVariants[normalizedLink]
+		children := make([]string, 0, len(spellings))
+		for spelling, count := range spellings {
+			children = append(children, spelling+" on "+strconv.Itoa(count)+" pages")
+		}
+		sort.Strings(children)
+		section.addWithChildren(normalizedLink, children, len(spellings), "spellings")
+	}
+	return report
+}
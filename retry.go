@@ -0,0 +1,95 @@
+package walker
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/foomo/walker/config"
+)
+
+type retryPolicy config.Retry
+
+// next tells if an attempt should be retried and how long we should wait before doing so
+func (rp retryPolicy) next(attempt int, resp *http.Response, err error) (wait time.Duration, retry bool) {
+	if attempt >= rp.MaxAttempts {
+		return 0, false
+	}
+	switch true {
+	case err != nil:
+		if !rp.NetworkErrors || !isTransientNetworkError(err) {
+			return 0, false
+		}
+	case resp != nil:
+		retryableCode := false
+		for _, code := range rp.StatusCodes {
+			if code == resp.StatusCode {
+				retryableCode = true
+				break
+			}
+		}
+		if !retryableCode {
+			return 0, false
+		}
+		if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			return rp.limit(retryAfter), true
+		}
+	}
+	wait = rp.Backoff
+	for i := 1; i < attempt; i++ {
+		wait *= 2
+	}
+	return rp.limit(wait), true
+}
+
+func (rp retryPolicy) limit(wait time.Duration) time.Duration {
+	if rp.MaxBackoff > 0 && wait > rp.MaxBackoff {
+		return rp.MaxBackoff
+	}
+	return wait
+}
+
+func isTransientNetworkError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	for _, transientErr := range []error{
+		syscall.ECONNRESET,
+		syscall.ECONNREFUSED,
+		syscall.ECONNABORTED,
+		io.EOF,
+		io.ErrUnexpectedEOF,
+	} {
+		if errors.Is(err, transientErr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRetryAfter supports both delay seconds and http dates
+func parseRetryAfter(retryAfter string, now time.Time) (wait time.Duration, ok bool) {
+	if retryAfter == "" {
+		return 0, false
+	}
+	seconds, errAtoi := strconv.Atoi(retryAfter)
+	if errAtoi == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, errParse := http.ParseTime(retryAfter)
+	if errParse != nil {
+		return 0, false
+	}
+	if date.Before(now) {
+		return 0, true
+	}
+	return date.Sub(now), true
+}
@@ -0,0 +1,120 @@
+package walker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/foomo/walker/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	wait, ok := parseRetryAfter("120", now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Minute*2, wait)
+	wait, ok = parseRetryAfter(now.Add(time.Second*30).Format(http.TimeFormat), now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Second*30, wait)
+	_, ok = parseRetryAfter("soon", now)
+	assert.False(t, ok)
+	_, ok = parseRetryAfter("", now)
+	assert.False(t, ok)
+}
+
+func TestScrapeRetry(t *testing.T) {
+	calls := 0
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch calls {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><title>finally</title></html>"))
+		}
+	}))
+	defer testServer.Close()
+	baseURL, _ := url.Parse(testServer.URL)
+	cp := newClientPool(1, "test", false, testHTTPClientSettings(config.HTTP{}), nil)
+	chanResult := make(chan scrapeResultAndClient, 1)
+	retry := retryPolicy(config.Retry{
+		MaxAttempts: 3,
+		Backoff:     time.Millisecond,
+		StatusCodes: []int{http.StatusBadGateway, http.StatusServiceUnavailable},
+	})
+	scrape(context.Background(), cp.clients[0], testServer.URL+"/", baseURL, "", nil, nil, nil, retry, nil, chanResult)
+	result := (<-chanResult).result
+	assert.Equal(t, http.StatusOK, result.Code)
+	assert.Equal(t, "finally", result.Structure.Title)
+	assert.Len(t, result.Attempts, 2)
+	assert.Equal(t, http.StatusServiceUnavailable, result.Attempts[0].Code)
+	assert.Equal(t, http.StatusBadGateway, result.Attempts[1].Code)
+
+	// out of attempts
+	calls = 0
+	retry.MaxAttempts = 2
+	scrape(context.Background(), cp.clients[0], testServer.URL+"/", baseURL, "", nil, nil, nil, retry, nil, chanResult)
+	result = (<-chanResult).result
+	assert.Equal(t, http.StatusBadGateway, result.Code)
+	assert.Len(t, result.Attempts, 1)
+}
+
+func TestScrapeCancel(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer testServer.Close()
+	baseURL, _ := url.Parse(testServer.URL)
+	cp := newClientPool(1, "test", false, testHTTPClientSettings(config.HTTP{}), nil)
+	chanResult := make(chan scrapeResultAndClient, 1)
+	retry := retryPolicy(config.Retry{
+		MaxAttempts: 10,
+		Backoff:     time.Minute,
+		StatusCodes: []int{http.StatusServiceUnavailable},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(time.Millisecond*50, cancel)
+	start := time.Now()
+	// the backoff must not outlive the context
+	scrape(ctx, cp.clients[0], testServer.URL+"/", baseURL, "", nil, nil, nil, retry, nil, chanResult)
+	result := (<-chanResult).result
+	assert.True(t, time.Since(start) < time.Second*5)
+	assert.Contains(t, result.Error, context.Canceled.Error())
+	assert.Len(t, result.Attempts, 1)
+}
+
+func TestScrapeRetryThrottle(t *testing.T) {
+	requests := []time.Time{}
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, time.Now())
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer testServer.Close()
+	baseURL, _ := url.Parse(testServer.URL)
+	cp := newClientPool(1, "test", false, testHTTPClientSettings(config.HTTP{}), nil)
+	chanResult := make(chan scrapeResultAndClient, 1)
+	retry := retryPolicy(config.Retry{
+		MaxAttempts: 3,
+		Backoff:     time.Millisecond,
+		StatusCodes: []int{http.StatusBadGateway},
+	})
+	thr := newThrottle(config.Politeness{RequestsPerSecond: 10}, 0)
+	// the scrape loop took the slot of the first attempt
+	thr.take(baseURL.Host, time.Now())
+	scrape(context.Background(), cp.clients[0], testServer.URL+"/", baseURL, "", nil, nil, nil, retry, thr, chanResult)
+	result := (<-chanResult).result
+	assert.Equal(t, http.StatusBadGateway, result.Code)
+	require.Len(t, requests, 3)
+	for i := 1; i < len(requests); i++ {
+		assert.True(t, requests[i].Sub(requests[i-1]) >= time.Millisecond*90, requests[i].Sub(requests[i-1]))
+	}
+}
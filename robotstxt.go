@@ -6,11 +6,16 @@ import (
 	"github.com/temoto/robotstxt"
 )
 
-func getRobotsData(baseURL string) (data *robotstxt.RobotsData, err error) {
-	resp, errGet := http.Get(baseURL + "/robots.txt")
+func getRobotsData(pc *poolClient, baseURL string) (data *robotstxt.RobotsData, err error) {
+	req, errRequest := pc.newRequest(http.MethodGet, baseURL+"/robots.txt")
+	if errRequest != nil {
+		return nil, errRequest
+	}
+	resp, errGet := pc.client.Do(req)
 	if errGet != nil {
 		return nil, errGet
 	}
+	defer resp.Body.Close()
 	data, errFromResponse := robotstxt.FromResponse(resp)
 	if errFromResponse != nil {
 		return nil, errFromResponse
@@ -0,0 +1,272 @@
+package walker
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/foomo/walker/config"
+)
+
+const (
+	ruleActionInclude = "include"
+	ruleActionExclude = "exclude"
+)
+
+var queryConditionRegex = regexp.MustCompile(`^\s*(!?)([^\s<>=!~]+)\s*(?:(=|!=|<=|>=|<|>|~)\s*(.*?))?\s*$`)
+
+type queryCondition struct {
+	param string
+	// "", "!", =, !=, <, <=, >, >=, ~
+	op     string
+	value  string
+	number float64
+	regex  *regexp.Regexp
+}
+
+type linkRule struct {
+	name    string
+	include bool
+	regex   *regexp.Regexp
+	glob    *regexp.Regexp
+	query   []queryCondition
+	limit   int
+	// links included so far, the limit counts distinct links
+	included map[string]bool
+}
+
+// linkRules are evaluated in order, the first matching rule decides
+type linkRules []*linkRule
+
+func newLinkRules(rules []config.Rule) (lr linkRules, err error) {
+	for i, rule := range rules {
+		r, errRule := newLinkRule(rule)
+		if errRule != nil {
+			return nil, fmt.Errorf("rules[%d]: %s", i, errRule)
+		}
+		lr = append(lr, r)
+	}
+	return lr, nil
+}
+
+func newLinkRule(rule config.Rule) (r *linkRule, err error) {
+	r = &linkRule{
+		name:     rule.Name,
+		limit:    rule.Limit,
+		included: map[string]bool{},
+	}
+	switch rule.Action {
+	case ruleActionInclude:
+		r.include = true
+	case ruleActionExclude:
+		if rule.Limit > 0 {
+			return nil, errors.New("only include rules can have a limit")
+		}
+	default:
+		return nil, errors.New("action has to be include or exclude, got: " + rule.Action)
+	}
+	conditions := []string{}
+	if rule.Regex != "" {
+		regex, errRegex := regexp.Compile(rule.Regex)
+		if errRegex != nil {
+			return nil, errRegex
+		}
+		r.regex = regex
+		conditions = append(conditions, "regex "+rule.Regex)
+	}
+	if rule.Glob != "" {
+		glob, errGlob := compileGlob(rule.Glob)
+		if errGlob != nil {
+			return nil, errGlob
+		}
+		r.glob = glob
+		conditions = append(conditions, "glob "+rule.Glob)
+	}
+	for _, query := range rule.Query {
+		condition, errCondition := parseQueryCondition(query)
+		if errCondition != nil {
+			return nil, errCondition
+		}
+		r.query = append(r.query, condition)
+		conditions = append(conditions, "query "+query)
+	}
+	if len(conditions) == 0 {
+		return nil, errors.New("a rule needs a regex, a glob or a query condition")
+	}
+	if r.name == "" {
+		r.name = rule.Action + " " + strings.Join(conditions, " and ")
+	}
+	return r, nil
+}
+
+// compileGlob turns a glob into an anchored regular expression, * does not match /, ** does
+func compileGlob(glob string) (*regexp.Regexp, error) {
+	expression := "^"
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				expression += ".*"
+				i++
+			} else {
+				expression += "[^/]*"
+			}
+		case '?':
+			expression += "[^/]"
+		default:
+			expression += regexp.QuoteMeta(string(c))
+		}
+	}
+	return regexp.Compile(expression + "$")
+}
+
+func parseQueryCondition(condition string) (qc queryCondition, err error) {
+	matches := queryConditionRegex.FindStringSubmatch(condition)
+	if matches == nil {
+		return qc, errors.New("invalid query condition: " + condition)
+	}
+	qc.param = matches[2]
+	qc.op = matches[3]
+	qc.value = matches[4]
+	if matches[1] == "!" {
+		if qc.op != "" {
+			return qc, errors.New("! only works without an operator: " + condition)
+		}
+		qc.op = "!"
+	}
+	switch qc.op {
+	case "<", "<=", ">", ">=":
+		number, errNumber := strconv.ParseFloat(qc.value, 64)
+		if errNumber != nil {
+			return qc, errors.New("not a number in query condition: " + condition)
+		}
+		qc.number = number
+	case "~":
+		regex, errRegex := regexp.Compile(qc.value)
+		if errRegex != nil {
+			return qc, errRegex
+		}
+		qc.regex = regex
+	}
+	return qc, nil
+}
+
+// matches, if any value of the parameter matches
+func (qc queryCondition) matches(query url.Values) bool {
+	values, ok := query[qc.param]
+	switch qc.op {
+	case "":
+		return ok
+	case "!":
+		return !ok
+	case "!=":
+		for _, value := range values {
+			if value == qc.value {
+				return false
+			}
+		}
+		return ok
+	}
+	for _, value := range values {
+		switch qc.op {
+		case "=":
+			if value == qc.value {
+				return true
+			}
+		case "~":
+			if qc.regex.MatchString(value) {
+				return true
+			}
+		default:
+			number, errNumber := strconv.ParseFloat(value, 64)
+			if errNumber != nil {
+				continue
+			}
+			if (qc.op == "<" && number < qc.number) ||
+				(qc.op == "<=" && number <= qc.number) ||
+				(qc.op == ">" && number > qc.number) ||
+				(qc.op == ">=" && number >= qc.number) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *linkRule) matches(u *url.URL) bool {
+	if r.regex != nil && !r.regex.MatchString(u.Path) {
+		return false
+	}
+	if r.glob != nil && !r.glob.MatchString(u.Path) {
+		return false
+	}
+	if len(r.query) > 0 {
+		query := u.Query()
+		for _, qc := range r.query {
+			if !qc.matches(query) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// match finds the first matching rule, nil if none matches
+func (lr linkRules) match(u *url.URL) *linkRule {
+	for _, r := range lr {
+		if r.matches(u) {
+			return r
+		}
+	}
+	return nil
+}
+
+// limited tells, if the limit of the rule does not leave room for the link
+func (r *linkRule) limited(link string) (limited bool, reason string) {
+	if r.limit > 0 && !r.included[link] && len(r.included) >= r.limit {
+		return true, "limit of " + strconv.Itoa(r.limit) + " reached for rule " + r.name
+	}
+	return false, ""
+}
+
+// decide about a link, matched is false, if no rule matched, only include counts towards the limits
+func (lr linkRules) decide(u *url.URL) (matched, follow bool, reason string) {
+	r := lr.match(u)
+	if r == nil {
+		return false, false, ""
+	}
+	if !r.include {
+		return true, false, "excluded by rule " + r.name
+	}
+	if limited, limitReason := r.limited(u.String()); limited {
+		return true, false, limitReason
+	}
+	return true, true, "included by rule " + r.name
+}
+
+// include counts a link, that is about to be enqueued, towards the limit of its rule, ok is false, if there is no room left
+func (lr linkRules) include(link string) (ok bool, reason string) {
+	u, errParse := url.Parse(link)
+	if errParse != nil {
+		return true, ""
+	}
+	r := lr.match(u)
+	if r == nil || !r.include || r.limit == 0 {
+		return true, ""
+	}
+	if limited, limitReason := r.limited(link); limited {
+		return false, limitReason
+	}
+	r.included[link] = true
+	return true, ""
+}
+
+// reset the limits for a new loop
+func (lr linkRules) reset() {
+	for _, r := range lr {
+		r.included = map[string]bool{}
+	}
+}
@@ -0,0 +1,145 @@
+package walker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/foomo/walker/config"
+	"github.com/foomo/walker/vo"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkRules(t *testing.T) {
+	rules, errRules := newLinkRules([]config.Rule{
+		{Action: "exclude", Glob: "/*/filter/**"},
+		{Action: "exclude", Query: []string{"page > 5"}},
+		{Action: "include", Regex: "^/p/[0-9]+$", Limit: 2, Name: "products"},
+		{Action: "exclude", Regex: "^/p/"},
+	})
+	require.NoError(t, errRules)
+	baseURL, _ := url.Parse("http://example.com/")
+	decisions := map[string]vo.LinkDecision{}
+	ll := linkLimitations{
+		includePathPrefixes: []string{"/shop"},
+		ignorePathPrefixes:  []string{"/shop/private"},
+		rules:               rules,
+		decisions:           decisions,
+	}
+	links := filterScrapeLinks(vo.LinkList{
+		"/shop/filter/red":   1,
+		"/shop/shoes":        1,
+		"/shop/shoes?page=6": 1,
+		"/shop/shoes?page=5": 1,
+		"/shop/private":      1,
+		"/p/1":               1,
+		"/p/2":               1,
+		"/p/3":               1,
+		"/p/x":               1,
+		"/about":             1,
+		"http://other.com/":  1,
+	}, baseURL, "", "", ll, nil)
+	assert.Len(t, links, 5)
+	assert.Contains(t, links, "http://example.com/shop/shoes")
+	assert.Contains(t, links, "http://example.com/shop/shoes?page=5")
+	assert.Equal(t, vo.LinkDecision{Follow: false, Reason: "excluded by rule exclude glob /*/filter/**"}, decisions["http://example.com/shop/filter/red"])
+	assert.Equal(t, vo.LinkDecision{Follow: false, Reason: "excluded by rule exclude query page > 5"}, decisions["http://example.com/shop/shoes?page=6"])
+	assert.Equal(t, vo.LinkDecision{Follow: false, Reason: "ignored path prefix /shop/private"}, decisions["http://example.com/shop/private"])
+	assert.Equal(t, vo.LinkDecision{Follow: false, Reason: "not in the paths of the target"}, decisions["http://example.com/about"])
+	assert.Equal(t, vo.LinkDecision{Follow: false, Reason: "external link"}, decisions["http://other.com/"])
+	assert.Equal(t, "excluded by rule exclude regex ^/p/", decisions["http://example.com/p/x"].Reason)
+	assert.Equal(t, vo.LinkDecision{Follow: true, Reason: "included by rule products"}, decisions["http://example.com/p/3"])
+
+	// the limit counts enqueued links only
+	for _, link := range []string{"http://example.com/p/1", "http://example.com/p/2"} {
+		ok, _ := rules.include(link)
+		assert.True(t, ok, link)
+	}
+	ok, reason := rules.include("http://example.com/p/3")
+	assert.False(t, ok)
+	assert.Equal(t, "limit of 2 reached for rule products", reason)
+	ok, _ = rules.include("http://example.com/shop/shoes")
+	assert.True(t, ok)
+
+	// links, that were included before, stay included
+	again := filterScrapeLinks(vo.LinkList{"/p/1": 1, "/p/2": 1, "/p/3": 1}, baseURL, "", "", ll, nil)
+	assert.Len(t, again, 2)
+	assert.Equal(t, "limit of 2 reached for rule products", decisions["http://example.com/p/3"].Reason)
+	rules.reset()
+	assert.Len(t, filterScrapeLinks(vo.LinkList{"/p/3": 1}, baseURL, "", "", ll, nil), 1)
+}
+
+func TestLinkRulesLimitWalk(t *testing.T) {
+	pages := map[string][]string{
+		"/":    {"/p/1", "/b"},
+		"/b":   {"/p/2"},
+		"/p/1": {},
+		"/p/2": {},
+	}
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		links, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		html := "<html><body>"
+		for _, link := range links {
+			html += `<a href="` + link + `">` + link + `</a>`
+		}
+		w.Write([]byte(html + "</body></html>"))
+	}))
+	defer testServer.Close()
+	w, errNew := New(WithRegisterer(prometheus.NewRegistry()))
+	require.NoError(t, errNew)
+	defer w.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	chanStatus, errWalk := w.WalkContext(ctx, &config.Config{
+		// /p/1 is a start page, finding it again does not enqueue it
+		Target:       config.Target{BaseURL: testServer.URL, Paths: []string{"/", "/p/1"}},
+		IgnoreRobots: true,
+		Once:         true,
+		Concurrency:  1,
+		Rules:        []config.Rule{{Action: "include", Regex: "^/p/", Limit: 1}},
+	})
+	require.NoError(t, errWalk)
+	select {
+	case status := <-chanStatus:
+		assert.Len(t, status.Results, 4)
+		assert.Contains(t, status.Results, testServer.URL+"/p/2")
+	case <-ctx.Done():
+		t.Fatal("walk did not complete")
+	}
+}
+
+func TestQueryConditions(t *testing.T) {
+	query := url.Values{"page": {"3"}, "sort": {"price"}}
+	for condition, expected := range map[string]bool{
+		"page":         true,
+		"!page":        false,
+		"!session":     true,
+		"page > 2":     true,
+		"page >= 4":    false,
+		"page<4":       true,
+		"sort = price": true,
+		"sort != name": true,
+		"sort ~ ^pr":   true,
+		"missing = x":  false,
+	} {
+		qc, errCondition := parseQueryCondition(condition)
+		require.NoError(t, errCondition, condition)
+		assert.Equal(t, expected, qc.matches(query), condition)
+	}
+	_, errCondition := parseQueryCondition("page > five")
+	assert.Error(t, errCondition)
+	_, errRule := newLinkRule(config.Rule{Action: "skip", Glob: "/*"})
+	assert.Error(t, errRule)
+	_, errRule = newLinkRule(config.Rule{Action: "include"})
+	assert.Error(t, errRule)
+}
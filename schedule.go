@@ -0,0 +1,195 @@
+package walker
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/foomo/walker/config"
+)
+
+// how far we look ahead for a matching cron slot
+const cronHorizon = time.Hour * 24 * 366 * 5
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// schedule tells, when the next loop starts
+type schedule struct {
+	interval time.Duration
+	cron     *cronSchedule
+}
+
+// cronSchedule matches minutes in local time
+type cronSchedule struct {
+	minutes  []bool
+	hours    []bool
+	days     []bool
+	months   []bool
+	weekdays []bool
+	// * for days or weekdays, if both are restricted, either one has to match
+	anyDay     bool
+	anyWeekday bool
+}
+
+func newSchedule(conf config.Schedule) (s *schedule, err error) {
+	if conf.Empty() {
+		return nil, nil
+	}
+	if conf.Interval < 0 {
+		return nil, errors.New("schedule interval must not be negative")
+	}
+	s = &schedule{interval: conf.Interval}
+	if conf.Cron != "" {
+		cron, errCron := parseCron(conf.Cron)
+		if errCron != nil {
+			return nil, errCron
+		}
+		if cron.next(time.Now()).IsZero() {
+			return nil, errors.New("cron expression never matches: " + conf.Cron)
+		}
+		s.cron = cron
+	}
+	return s, nil
+}
+
+// next start of a loop, that started at lastStart and completed now
+func (s *schedule) next(lastStart, now time.Time) time.Time {
+	next := now
+	if s.interval > 0 {
+		if intervalStart := lastStart.Add(s.interval); intervalStart.After(next) {
+			next = intervalStart
+		}
+	}
+	if s.cron != nil {
+		// a slot right at the end of the interval is fine
+		next = s.cron.next(next.Add(-time.Nanosecond))
+	}
+	return next
+}
+
+func parseCron(expression string) (cron *cronSchedule, err error) {
+	expression = strings.TrimSpace(expression)
+	if macro, ok := cronMacros[expression]; ok {
+		expression = macro
+	}
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, errors.New("a cron expression needs 5 fields: minute hour day month weekday, got: " + expression)
+	}
+	cron = &cronSchedule{
+		anyDay:     fields[2] == "*",
+		anyWeekday: fields[4] == "*",
+	}
+	for _, f := range []struct {
+		field    string
+		min, max int
+		values   *[]bool
+	}{
+		{fields[0], 0, 59, &cron.minutes},
+		{fields[1], 0, 23, &cron.hours},
+		{fields[2], 1, 31, &cron.days},
+		{fields[3], 1, 12, &cron.months},
+		// 7 is sunday as well
+		{fields[4], 0, 7, &cron.weekdays},
+	} {
+		values, errField := parseCronField(f.field, f.min, f.max)
+		if errField != nil {
+			return nil, errors.New("invalid cron field " + strconv.Quote(f.field) + ": " + errField.Error())
+		}
+		*f.values = values
+	}
+	if cron.weekdays[7] {
+		cron.weekdays[0] = true
+	}
+	return cron, nil
+}
+
+// parseCronField parses lists of *, n, n-m with optional steps like */15 or 1-5/2
+func parseCronField(field string, min, max int) (values []bool, err error) {
+	values = make([]bool, max+1)
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if slash := strings.Index(part, "/"); slash > -1 {
+			step, err = strconv.Atoi(part[slash+1:])
+			if err != nil || step < 1 {
+				return nil, errors.New("invalid step in " + part)
+			}
+			part = part[:slash]
+		}
+		from, to := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			from, err = strconv.Atoi(bounds[0])
+			if err != nil {
+				return nil, err
+			}
+			to, err = strconv.Atoi(bounds[1])
+			if err != nil {
+				return nil, err
+			}
+		default:
+			from, err = strconv.Atoi(part)
+			if err != nil {
+				return nil, err
+			}
+			to = from
+			if step > 1 {
+				// 5/10 means from 5 on
+				to = max
+			}
+		}
+		if from < min || to > max || from > to {
+			return nil, errors.New("out of range " + strconv.Itoa(min) + "-" + strconv.Itoa(max) + ": " + part)
+		}
+		for v := from; v <= to; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+func (c *cronSchedule) dayMatches(t time.Time) bool {
+	day := c.days[t.Day()]
+	weekday := c.weekdays[int(t.Weekday())]
+	switch {
+	case c.anyDay && c.anyWeekday:
+		return true
+	case c.anyDay:
+		return weekday
+	case c.anyWeekday:
+		return day
+	}
+	return day || weekday
+}
+
+// next matching minute after t, the zero time, if there is none within the horizon
+func (c *cronSchedule) next(t time.Time) time.Time {
+	limit := t.Add(cronHorizon)
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	for t.Before(limit) {
+		switch {
+		case !c.months[int(t.Month())]:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case !c.hours[t.Hour()]:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case !c.minutes[t.Minute()]:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,56 @@
+package walker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/foomo/walker/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCronNext(t *testing.T) {
+	// a wednesday
+	now := time.Date(2020, 1, 1, 12, 30, 10, 0, time.UTC)
+	for expression, expected := range map[string]time.Time{
+		"* * * * *":       time.Date(2020, 1, 1, 12, 31, 0, 0, time.UTC),
+		"*/15 * * * *":    time.Date(2020, 1, 1, 12, 45, 0, 0, time.UTC),
+		"0 3 * * *":       time.Date(2020, 1, 2, 3, 0, 0, 0, time.UTC),
+		"@hourly":         time.Date(2020, 1, 1, 13, 0, 0, 0, time.UTC),
+		"0 0 * * 1-5":     time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+		"0 0 * * 7":       time.Date(2020, 1, 5, 0, 0, 0, 0, time.UTC),
+		"30 6 15 * *":     time.Date(2020, 1, 15, 6, 30, 0, 0, time.UTC),
+		"0 0 29 2 *":      time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC),
+		"5,10 12 * 1,6 *": time.Date(2020, 1, 2, 12, 5, 0, 0, time.UTC),
+		// either the day or the weekday
+		"0 0 10 * 5": time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC),
+	} {
+		cron, errCron := parseCron(expression)
+		require.NoError(t, errCron, expression)
+		assert.Equal(t, expected, cron.next(now), expression)
+	}
+	for _, expression := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "a * * * *", "5-1 * * * *"} {
+		_, errCron := parseCron(expression)
+		assert.Error(t, errCron, expression)
+	}
+	_, errNever := newSchedule(config.Schedule{Cron: "0 0 30 2 *"})
+	assert.Error(t, errNever)
+}
+
+func TestScheduleNext(t *testing.T) {
+	start := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	s, errSchedule := newSchedule(config.Schedule{Interval: time.Hour})
+	require.NoError(t, errSchedule)
+	assert.Equal(t, start.Add(time.Hour), s.next(start, start.Add(time.Minute*10)))
+	// overdue loops start right away
+	assert.Equal(t, start.Add(time.Hour*2), s.next(start, start.Add(time.Hour*2)))
+
+	s, errSchedule = newSchedule(config.Schedule{Interval: time.Hour, Cron: "0 * * * *"})
+	require.NoError(t, errSchedule)
+	assert.Equal(t, start.Add(time.Hour), s.next(start, start.Add(time.Minute*10)))
+	assert.Equal(t, start.Add(time.Hour*2), s.next(start, start.Add(time.Minute*70)))
+
+	s, errSchedule = newSchedule(config.Schedule{})
+	assert.NoError(t, errSchedule)
+	assert.Nil(t, s)
+}
@@ -3,8 +3,11 @@ package walker
 import (
 	"bytes"
 	"context"
+	"errors"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"strings"
 	"time"
@@ -31,6 +34,7 @@ func newScrapeResultandClient(r vo.ScrapeResult, pc *poolClient) scrapeResultAnd
 }
 
 func scrape(
+	ctx context.Context,
 	pc *poolClient,
 	targetURL string,
 	baseURL *url.URL,
@@ -38,6 +42,8 @@ func scrape(
 	scrapeFunc ScrapeFunc,
 	validationFunc ValidationFunc,
 	groupValidator *htmlschema.GroupValidator,
+	retry retryPolicy,
+	thr *throttle,
 	chanResult chan scrapeResultAndClient,
 ) {
 	result := vo.ScrapeResult{
@@ -46,22 +52,81 @@ func scrape(
 		Group:     "default",
 	}
 	var doc *goquery.Document
-	start := time.Now()
+	var req *http.Request
+	var resp *http.Response
+	var errGet error
+	var start time.Time
+	var timer *requestTimer
+	// a session is renewed only once per scrape
+	loggedIn := false
 
-	req, errRequest := http.NewRequest("GET", targetURL, nil)
-	if errRequest != nil {
-		result.Error = errRequest.Error()
-		chanResult <- newScrapeResultandClient(result, pc)
-		return
-	}
-	if baseURL.User != nil {
-		req.URL.User = baseURL.User
+	for attempt := 1; ; attempt++ {
+		nextReq, errRequest := pc.newRequest(http.MethodGet, targetURL)
+		if errRequest != nil {
+			result.Error = errRequest.Error()
+			chanResult <- newScrapeResultandClient(result, pc)
+			return
+		}
+		req = nextReq
+		if baseURL.User != nil {
+			req.URL.User = baseURL.User
+		}
+		timer = newRequestTimer()
+		req = req.WithContext(httptrace.WithClientTrace(ctx, timer.clientTrace()))
+		start = time.Now()
+		resp, errGet = pc.client.Do(req)
+		timer.gotHeaders()
+		if errGet == nil && pc.auth != nil && !loggedIn && pc.auth.expired(resp) {
+			loggedIn = true
+			result.Attempts = append(result.Attempts, vo.Attempt{
+				Time:     start,
+				Code:     resp.StatusCode,
+				Status:   "session expired",
+				Duration: time.Since(start),
+			})
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+			errLogin := pc.auth.login(ctx, pc)
+			if errLogin != nil {
+				errGet = errors.New("session expired, login failed: " + errLogin.Error())
+				break
+			}
+			// logging in again is not a retry
+			attempt--
+			continue
+		}
+		wait, retryAttempt := retry.next(attempt, resp, errGet)
+		if !retryAttempt || ctx.Err() != nil {
+			break
+		}
+		failedAttempt := vo.Attempt{
+			Time:     start,
+			Duration: time.Since(start),
+		}
+		if errGet != nil {
+			failedAttempt.Error = errGet.Error()
+		} else {
+			failedAttempt.Code = resp.StatusCode
+			failedAttempt.Status = resp.Status
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		result.Attempts = append(result.Attempts, failedAttempt)
+		if thr != nil {
+			// retries are requests like any other
+			wait += thr.reserve(req.URL.Host, time.Now().Add(wait))
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+		}
 	}
-	req.Header.Set("User-Agent", pc.agent)
-	req = req.WithContext(context.TODO())
-	resp, errGet := pc.client.Do(req)
 	if errGet != nil {
 		result.Error = errGet.Error()
+		if tlsError(errGet) {
+			result.TLS = &vo.TLSInfo{Problems: []string{errGet.Error()}}
+		}
+		result.Timing = timer.done()
 		chanResult <- newScrapeResultandClient(result, pc)
 		return
 	}
@@ -69,8 +134,12 @@ func scrape(
 	result.Code = resp.StatusCode
 	result.Status = resp.Status
 	result.Redirects = getRedirectsFromRequest(resp.Request)
+	if resp.TLS != nil {
+		result.TLS = pc.settings.inspector.inspect(resp.TLS, resp.Request.URL.Hostname(), start)
+	}
 	if resp.Body == nil {
 		result.Error = ErrorNoBody
+		result.Timing = timer.done()
 		chanResult <- newScrapeResultandClient(result, pc)
 		return
 	}
@@ -88,12 +157,13 @@ func scrape(
 	}
 	if strings.Contains(result.ContentType, "html") {
 		bodyBytes, errReadAll := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		result.Timing = timer.done()
 		if errReadAll != nil {
 			result.Error = errReadAll.Error()
 			chanResult <- newScrapeResultandClient(result, pc)
 			return
 		}
-		resp.Body.Close()
 
 		bodyReadCloser := ioutil.NopCloser(bytes.NewBuffer(bodyBytes))
 
@@ -121,10 +191,14 @@ func scrape(
 		}
 		result.Links = linkList
 		result.NormalizedLinks = normalizedLinkList
+		result.FragmentLinks = extractFragmentLinks(linkList, baseURL, targetURL)
+		result.Anchors = extractAnchors(doc)
+		result.Assets = extractAssets(doc, baseURL)
 
 		structure, errExtractStructure := ExtractStructure(doc)
 		if errExtractStructure != nil {
 			result.Error = errExtractStructure.Error()
+			chanResult <- newScrapeResultandClient(result, pc)
 			return
 		}
 		result.Structure = structure
@@ -143,11 +217,17 @@ func scrape(
 			validations, errValidate := validationFunc(result.Structure, result.Data)
 			if errValidate != nil {
 				result.Error = errValidate.Error()
+				chanResult <- newScrapeResultandClient(result, pc)
 				return
 			}
 			result.Validations = validations
 		}
 
+	} else {
+		// transfer it anyways - we want to know how long that takes and the connection can be reused
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+		result.Timing = timer.done()
 	}
 
 	r := newScrapeResultandClient(result, pc)
@@ -2,37 +2,68 @@ package walker
 
 import (
 	"context"
-	"crypto/tls"
 	"errors"
 	"fmt"
-	"net"
+	"io"
 	"net/http"
-	"net/http/cookiejar"
 	"net/url"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/PuerkitoBio/goquery"
+	"github.com/foomo/walker/config"
 	"github.com/foomo/walker/htmlschema"
 	"github.com/foomo/walker/vo"
 	"github.com/temoto/robotstxt"
 )
 
 type poolClient struct {
-	agent  string
-	client *http.Client
-	busy   bool
+	agent    string
+	client   *http.Client
+	settings *httpClientSettings
+	auth     *authenticator
 }
 
+// clientPool has a worker for every client, the workers scrape the jobs of chanJobs
 type clientPool struct {
 	agent       string
 	concurrency int
 	useCookies  bool
+	settings    *httpClientSettings
+	auth        *authenticator
 	clients     []*poolClient
+	chanJobs    chan scrapeJob
+}
+
+// sitemapSeed are the sitemap entries for a loop
+type sitemapSeed struct {
+	loop    int
+	urls    []string
+	entries map[string]vo.SitemapEntry
+	err     error
+}
+
+// scrapeJob has everything a worker needs for a scrape
+type scrapeJob struct {
+	ctx            context.Context
+	targetURL      string
+	baseURL        *url.URL
+	groupHeader    string
+	scrapeFunc     ScrapeFunc
+	validationFunc ValidationFunc
+	groupValidator *htmlschema.GroupValidator
+	retry          retryPolicy
+	thr            *throttle
 }
 
 type contextKeyRedirects struct{}
 
+// how often the frontier is written to the store
+const checkpointInterval = time.Second * 5
+
 func getRedirectsFromRequest(r *http.Request) []vo.Redirect {
 	via := r.Context().Value(contextKeyRedirects{})
 	if via != nil {
@@ -41,52 +72,14 @@ func getRedirectsFromRequest(r *http.Request) []vo.Redirect {
 	return []vo.Redirect{}
 }
 
-func newClientPool(concurrency int, agent string, useCookies bool) *clientPool {
+func newClientPool(concurrency int, agent string, useCookies bool, settings *httpClientSettings, auth *authenticator) *clientPool {
 	clients := make([]*poolClient, concurrency)
 	for i := 0; i < concurrency; i++ {
-		client := &http.Client{
-			Timeout: time.Second * 10,
-			Transport: &http.Transport{
-				DialContext: (&net.Dialer{
-					Timeout:   5 * time.Second,
-					KeepAlive: 5 * time.Second,
-				}).DialContext,
-				MaxIdleConns:          100,
-				MaxIdleConnsPerHost:   50,
-				IdleConnTimeout:       15 * time.Second,
-				TLSHandshakeTimeout:   5 * time.Second,
-				ExpectContinueTimeout: 1 * time.Second,
-				TLSClientConfig: &tls.Config{
-					// disable SSL certificate verification
-					InsecureSkipVerify: true,
-				},
-			},
-
-			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				if len(via) > 9 {
-					return errors.New("stopped after 10 redirects")
-				}
-				c := req.Context()
-				vias := getRedirectsFromRequest(req)
-				newR := req.WithContext(
-					context.WithValue(
-						c,
-						contextKeyRedirects{}, append(vias, vo.Redirect{
-							Code: req.Response.StatusCode,
-							URL:  req.URL.String(),
-						})))
-				*req = *newR
-				return nil
-			},
-		}
-		if useCookies {
-			cookieJar, _ := cookiejar.New(nil)
-			client.Jar = cookieJar
-		}
 		clients[i] = &poolClient{
-			client: client,
-			busy:   false,
-			agent:  agent,
+			client:   settings.newClient(useCookies, auth),
+			agent:    agent,
+			settings: settings,
+			auth:     auth,
 		}
 	}
 	return &clientPool{
@@ -94,24 +87,88 @@ func newClientPool(concurrency int, agent string, useCookies bool) *clientPool {
 		concurrency: concurrency,
 		clients:     clients,
 		useCookies:  useCookies,
+		settings:    settings,
+		auth:        auth,
+	}
+}
+
+// start the workers, there is always room in chanJobs for as many jobs as there are workers
+func (cp *clientPool) start(chanResult chan scrapeResultAndClient) {
+	cp.chanJobs = make(chan scrapeJob, cp.concurrency)
+	for _, pc := range cp.clients {
+		go cp.work(pc, cp.chanJobs, chanResult)
+	}
+}
+
+func (cp *clientPool) work(pc *poolClient, chanJobs chan scrapeJob, chanResult chan scrapeResultAndClient) {
+	for job := range chanJobs {
+		scrape(job.ctx, pc, job.targetURL, job.baseURL, job.groupHeader, job.scrapeFunc, job.validationFunc, job.groupValidator, job.retry, job.thr, chanResult)
+	}
+}
+
+// stop the workers, when they are done with their jobs
+func (cp *clientPool) stop() {
+	if cp.chanJobs != nil {
+		close(cp.chanJobs)
+		cp.chanJobs = nil
 	}
 }
 
+// newRequest with the user agent, the configured headers and credentials for the target
+func (pc *poolClient) newRequest(method, targetURL string) (*http.Request, error) {
+	return pc.newRequestWithBody(method, targetURL, nil)
+}
+
+func (pc *poolClient) newRequestWithBody(method, targetURL string, body io.Reader) (*http.Request, error) {
+	req, errRequest := http.NewRequest(method, targetURL, body)
+	if errRequest != nil {
+		return nil, errRequest
+	}
+	if req.URL.Host == pc.settings.host {
+		for name, values := range pc.settings.headers {
+			req.Header[name] = values
+		}
+	}
+	req.Header.Set("User-Agent", pc.agent)
+	if pc.auth != nil {
+		pc.auth.authorize(req)
+	}
+	return req, nil
+}
+
 func (w *Walker) scrapeloop() {
-	summaryVec,
-		counterVec,
-		totalCounter,
-		progressGaugeOpen,
-		progressGaugeComplete,
-		counterVecStatus,
-		trackValidationScore,
-		trackValidationPenalties := setupMetrics()
+	defer close(w.chanDone)
+	m := w.metrics
 	running := 0
 	concurrency := 0
 	groupHeader := ""
 	ignoreRobots := false
+	explainLinks := false
+	once := false
 	scrapeLoopStarted := false
+	paused := false
+	halted := false
+	// last complete status to diff against
+	var previous *vo.Status
+	var history *History
+	var sched *schedule
+	// when the current loop started and when the next one is due
+	loopStart := time.Time{}
+	nextRun := time.Time{}
+	historyStats := func() []vo.HistoryStats {
+		if history == nil {
+			return nil
+		}
+		stats, errStats := history.Stats(0)
+		if errStats != nil {
+			fmt.Println("could not load history", errStats)
+		}
+		return stats
+	}
 	var chanLoopComplete chan vo.Status
+	// the context of the current walk, scrapes are cancelled with it
+	walkCtx, cancelWalk := context.WithCancel(context.Background())
+	var walkDone <-chan struct{}
 	var scrapeFunc ScrapeFunc
 	var validationFunc ValidationFunc
 	var linkListFilterFunc LinkListFilterFunc
@@ -124,12 +181,244 @@ func (w *Walker) scrapeloop() {
 	var cp *clientPool
 	var robotsGroup *robotstxt.Group
 	var groupValidator *htmlschema.GroupValidator
+	var store Store
+	var thr *throttle
+	var retry retryPolicy
+	var sitemapConf config.Sitemap
+	var robotsSitemaps []string
+	var sitemap map[string]vo.SitemapEntry
+	// sitemaps are loaded in the background, a loop is not complete without them
+	sitemapPending := false
+	chanSitemap := make(chan sitemapSeed)
+	loopID := 0
+	var linkVariants map[string]map[string]int
+	// clicks from the start pages
+	var hops map[string]int
+	var budget config.Budget
+	// pages per group and pages, that were dropped, because their group is over budget
+	var groupPages map[string]int
+	var overBudget map[string]bool
+	// groups of pages, that were not fetched yet
+	var groupGuess *groupGuesser
+	budgetExhausted := ""
+	budgetDropped := 0
+	// pending jobs in the order they will be fetched
+	var front *frontier
+	var priority *jobPriority
+	depthFirst := false
+	var frontierConf config.Frontier
+	// spilled jobs come back with their current priority and hops
+	var pendingJob pendingFunc
+	resetFrontier := func() {
+		if front != nil {
+			front.close()
+		}
+		front = newFrontier(depthFirst, frontierConf.MaxJobs, frontierConf.SpillDir, pendingJob)
+	}
+	var discovery map[string]vo.Discovery
+	var forbidden *forbiddenMatcher
+	var externalLinksConf config.ExternalLinks
+	var externalLinkChecker *linkChecker
+	var assetsConf config.Assets
+	var assetChecker *linkChecker
+	defer func() {
+		if externalLinkChecker != nil {
+			externalLinkChecker.stop()
+		}
+		if assetChecker != nil {
+			assetChecker.stop()
+		}
+	}()
+	jobsDirty := false
+	lastCheckpoint := time.Time{}
+
+	// wait for all running scrapes, so that no worker is left blocking on w.chanResult
+	drain := func() {
+		for running > 0 {
+			<-w.chanResult
+			running--
+		}
+	}
+
+	// stores from WithStore belong to the caller
+	closeStore := func() {
+		if store == w.store {
+			return
+		}
+		errClose := store.Close()
+		if errClose != nil {
+			fmt.Println("could not close store", errClose)
+		}
+	}
+
+	shutdown := func() {
+		cancelWalk()
+		drain()
+		if cp != nil {
+			cp.stop()
+		}
+		if front != nil {
+			front.close()
+		}
+		if store != nil {
+			closeStore()
+		}
+		if chanLoopComplete != nil {
+			close(chanLoopComplete)
+			chanLoopComplete = nil
+		}
+	}
+	defer shutdown()
+
+	checkpoint := func() {
+		if store == nil {
+			return
+		}
+		errSave := store.SaveJobs(baseURL.String(), paths, jobs)
+		if errSave != nil {
+			fmt.Println("could not save jobs", errSave)
+			return
+		}
+		jobsDirty = false
+		lastCheckpoint = time.Now()
+	}
+
+	// what is known about a job right now
+	jobFor := func(jobURL string) Job {
+		job := Job{
+			URL:       jobURL,
+			Hops:      -1,
+			Discovery: discovery[jobURL],
+		}
+		if hop, ok := hops[jobURL]; ok {
+			job.Hops = hop
+		}
+		if entry, ok := sitemap[jobURL]; ok {
+			job.SitemapPriority = entry.Priority
+		}
+		return job
+	}
+
+	// queue a pending job or update its place in the frontier, spilled jobs are updated, when they come back
+	queueJob := func(jobURL string) {
+		job := jobFor(jobURL)
+		front.push(jobURL, priority.priority(job), job.Hops)
+	}
+
+	pendingJob = func(jobURL string) (float64, int, bool) {
+		if jobActive, ok := jobs[jobURL]; !ok || jobActive {
+			return 0, 0, false
+		}
+		job := jobFor(jobURL)
+		return priority.priority(job), job.Hops, true
+	}
 
-	restart := func(startURL *url.URL, configPaths []string) {
+	// shorter paths to pages also shorten the paths to the pages they link to
+	var relax func(link string, hop int)
+
+	// addJobs found hop clicks away from the start pages, -1 if not found by clicks
+	addJobs := func(linksToScrape vo.LinkList, discoveredVia vo.Discovery, hop int) {
+		// the frontier keeps the order, in which jobs were found
+		links := make([]string, 0, len(linksToScrape))
+		for linkToScrape := range linksToScrape {
+			links = append(links, linkToScrape)
+		}
+		sort.Strings(links)
+		for _, linkToScrape := range links {
+			if overBudget[linkToScrape] {
+				continue
+			}
+			if _, known := hops[linkToScrape]; !known && hop >= 0 && budget.MaxHops > 0 && hop > budget.MaxHops {
+				if ll.decisions != nil {
+					ll.decisions[linkToScrape] = vo.LinkDecision{Follow: false, Reason: "more than " + strconv.Itoa(budget.MaxHops) + " hops"}
+				}
+				continue
+			}
+			existingResult, existingResultOK := results[linkToScrape]
+			_, existingJobOK := jobs[linkToScrape]
+			newJob := !existingResultOK && !existingJobOK
+			if newJob {
+				// rule limits count the links, that are actually enqueued
+				if ok, reason := ll.rules.include(linkToScrape); !ok {
+					if ll.decisions != nil {
+						ll.decisions[linkToScrape] = vo.LinkDecision{Follow: false, Reason: reason}
+					}
+					continue
+				}
+			}
+			discovery[linkToScrape] = discovery[linkToScrape].Add(discoveredVia)
+			if existingResultOK && existingResult.Discovery != discovery[linkToScrape] {
+				existingResult.Discovery = discovery[linkToScrape]
+				results[linkToScrape] = existingResult
+			}
+			if newJob {
+				jobs[linkToScrape] = false
+				jobsDirty = true
+			}
+			if hop >= 0 {
+				relax(linkToScrape, hop)
+			}
+			if newJob {
+				queueJob(linkToScrape)
+			}
+		}
+	}
+
+	seedSitemap := func() {
+		sitemapURLs := []string{}
+		for _, sitemapURL := range sitemapConf.URLs {
+			sitemapU, errNormalize := NormalizeLink(baseURL, sitemapURL)
+			if errNormalize == nil {
+				sitemapURLs = append(sitemapURLs, sitemapU.String())
+			}
+		}
+		if len(sitemapURLs) == 0 {
+			sitemapURLs = robotsSitemaps
+		}
+		if len(sitemapURLs) == 0 {
+			sitemapURLs = []string{baseURL.Scheme + "://" + baseURL.Host + "/sitemap.xml"}
+		}
+		// the loop keeps answering, while the sitemaps are loading
+		sitemapPending = true
+		go func(ctx context.Context, pc *poolClient, loop int) {
+			entries, errSitemap := getSitemapEntries(ctx, pc, sitemapURLs)
+			select {
+			case chanSitemap <- sitemapSeed{loop: loop, urls: sitemapURLs, entries: entries, err: errSitemap}:
+			case <-ctx.Done():
+			}
+		}(walkCtx, cp.clients[0], loopID)
+	}
+
+	addSitemap := func(seed sitemapSeed) {
+		sitemapPending = false
+		if seed.err != nil {
+			fmt.Println("could not load sitemaps", seed.urls, seed.err)
+		}
+		// keyed like the results
+		sitemap = make(map[string]vo.SitemapEntry, len(seed.entries))
+		for loc, entry := range seed.entries {
+			sitemap[ll.normalizer.normalizeString(loc)] = entry
+		}
+		sitemapLinks := vo.LinkList{}
+		for loc := range sitemap {
+			sitemapLinks[loc]++
+		}
+		if halted {
+			return
+		}
+		addJobs(filterScrapeLinks(sitemapLinks, baseURL, "", "", ll, robotsGroup), vo.DiscoverySitemap, -1)
+		fmt.Println("seeded", len(sitemap), "urls from sitemaps", seed.urls)
+	}
+
+	restart := func(startURL *url.URL, configPaths []string, resumeFrom *Checkpoint) {
 		scrapeLoopStarted = false
-		summaryVec.Reset()
-		counterVec.Reset()
-		counterVecStatus.Reset()
+		loopID++
+		sitemapPending = false
+		loopStart = time.Now()
+		nextRun = time.Time{}
+		m.summaryVec.Reset()
+		m.counterVec.Reset()
+		m.counterVecStatus.Reset()
 		baseURL = startURL
 		paths = configPaths
 		running = 0
@@ -144,14 +433,138 @@ func (w *Walker) scrapeloop() {
 			q = "?" + baseURL.RawQuery
 		}
 		jobs = map[string]bool{}
+		hops = map[string]int{}
 		for _, p := range paths {
-			jobs[baseURLString+p+q] = false
+			startJob := ll.normalizer.normalizeString(baseURLString + p + q)
+			jobs[startJob] = false
+			hops[startJob] = 0
+		}
+		groupPages = map[string]int{}
+		overBudget = map[string]bool{}
+		groupGuess = newGroupGuesser()
+		budgetExhausted = ""
+		budgetDropped = 0
+
+		discovery = map[string]vo.Discovery{}
+		for jobURL := range jobs {
+			discovery[jobURL] = vo.DiscoveryLinks
 		}
 
 		results = map[string]vo.ScrapeResult{}
+		sitemap = nil
+		linkVariants = map[string]map[string]int{}
+		ll.rules.reset()
+		ll.decisions = nil
+		if explainLinks {
+			ll.decisions = map[string]vo.LinkDecision{}
+		}
+		if resumeFrom != nil {
+			jobs = resumeFrom.Jobs
+			results = resumeFrom.Results
+			for targetURL, result := range results {
+				discovery[targetURL] = result.Discovery
+				if result.Hops >= 0 {
+					hops[targetURL] = result.Hops
+				}
+				groupPages[result.Group]++
+				groupGuess.learn(targetURL, result.Group)
+			}
+		}
+		resetFrontier()
+		startJobs := make([]string, 0, len(jobs))
+		for jobURL := range jobs {
+			startJobs = append(startJobs, jobURL)
+		}
+		sort.Strings(startJobs)
+		for _, jobURL := range startJobs {
+			queueJob(jobURL)
+		}
+		if sitemapConf.Seed {
+			seedSitemap()
+		}
+		if resumeFrom == nil && store != nil {
+			errReset := store.Reset()
+			if errReset != nil {
+				fmt.Println("could not reset store", errReset)
+			}
+			checkpoint()
+		}
 		scrapeLoopStarted = true
 	}
 
+	linksToFollow := func(result vo.ScrapeResult, doc *goquery.Document, docURL *url.URL) (linksToScrape vo.LinkList) {
+		if linkListFilterFunc != nil {
+			if result.Error != "" {
+				fmt.Println("there was an error", result.Error)
+			} else if doc != nil {
+				linksToScrapeFromFromLilterFunc, errFilterLinkList := linkListFilterFunc(baseURL, docURL, doc)
+				if errFilterLinkList != nil {
+					fmt.Println("aua", errFilterLinkList)
+				}
+				linksToScrape = linksToScrapeFromFromLilterFunc
+			} else {
+				// relaxing and resuming have no document, the filter picked the links before
+				linksToScrape = result.FilteredLinks
+			}
+		} else if ignoreRobots || !strings.Contains(result.Structure.Robots, "nofollow") {
+			linkNextNormalized := ""
+			linkPrevNormalized := ""
+			// should we follow the links
+			linkNextNormalizedURL, errNormalizeNext := NormalizeLink(baseURL, result.Structure.LinkNext)
+			if errNormalizeNext == nil {
+				linkNextNormalized = ll.normalizer.normalize(linkNextNormalizedURL).String()
+			}
+			linkPrevNormalizedURL, errNormalizedPrev := NormalizeLink(baseURL, result.Structure.LinkPrev)
+			if errNormalizedPrev == nil {
+				linkPrevNormalized = ll.normalizer.normalize(linkPrevNormalizedURL).String()
+			}
+
+			linksToScrape = filterScrapeLinks(result.Links, baseURL, linkNextNormalized, linkPrevNormalized, ll, robotsGroup)
+		}
+		return linksToScrape
+	}
+
+	relax = func(link string, hop int) {
+		if known, ok := hops[link]; ok && known <= hop {
+			return
+		}
+		hops[link] = hop
+		if _, queued := front.byURL[link]; queued {
+			queueJob(link)
+		}
+		result, ok := results[link]
+		if !ok {
+			return
+		}
+		result.Hops = hop
+		results[link] = result
+		addJobs(linksToFollow(result, nil, nil), vo.DiscoveryLinks, hop+1)
+	}
+
+	// hops of the pages linking to a page plus one, -1 if we do not know
+	nextHop := func(result vo.ScrapeResult) int {
+		if result.Hops < 0 {
+			return -1
+		}
+		return result.Hops + 1
+	}
+
+	resume := func(startURL *url.URL, configPaths []string, resumeFrom *Checkpoint) {
+		restart(startURL, configPaths, resumeFrom)
+		// the limits count, what was enqueued before
+		for jobURL := range jobs {
+			ll.rules.include(jobURL)
+		}
+		for targetURL, result := range results {
+			ll.rules.include(targetURL)
+			delete(jobs, targetURL)
+			// links found after the last checkpoint of the jobs
+			addJobs(linksToFollow(result, nil, nil), vo.DiscoveryLinks, nextHop(result))
+		}
+		fmt.Println("resuming", baseURL, paths, "with", len(jobs), "jobs and", len(results), "results")
+		checkpoint()
+	}
+
 	getStatus := func() vo.Status {
 		resultsCopy := make(map[string]vo.ScrapeResult, len(results))
 		jobsCopy := make(map[string]bool, len(jobs))
@@ -187,7 +600,7 @@ func (w *Walker) scrapeloop() {
 		}
 		currentScrapeWindowSeconds := now.Unix() - scrapeWindowFirst
 		scrapeTotalSeconds := now.Unix() - first
-		return vo.Status{
+		status := vo.Status{
 			Results:              resultsCopy,
 			ScrapeSpeed:          float64(scrapeWindowCount) / float64(currentScrapeWindowSeconds),
 			ScrapeSpeedAverage:   float64(totalCount) / float64(scrapeTotalSeconds),
@@ -196,123 +609,483 @@ func (w *Walker) scrapeloop() {
 			ScrapeTotalRequests:  totalCount,
 			ScrapeTotalSeconds:   scrapeTotalSeconds,
 			Jobs:                 jobsCopy,
+			Sitemap:              sitemap,
+			LinkVariants:         make(map[string]map[string]int, len(linkVariants)),
+		}
+		status.Hops = make(map[string]int, len(hops))
+		for link, hop := range hops {
+			status.Hops[link] = hop
+		}
+		status.Budget = budgetExhausted
+		status.BudgetDropped = budgetDropped
+		if front != nil && front.err != nil {
+			status.FrontierError = front.err.Error()
+		}
+		if ll.decisions != nil {
+			status.LinkDecisions = make(map[string]vo.LinkDecision, len(ll.decisions))
+			for link, decision := range ll.decisions {
+				status.LinkDecisions[link] = decision
+			}
+		}
+		for normalizedLink, spellings := range linkVariants {
+			spellingsCopy := make(map[string]int, len(spellings))
+			for spelling, count := range spellings {
+				spellingsCopy[spelling] = count
+			}
+			status.LinkVariants[normalizedLink] = spellingsCopy
+		}
+		if externalLinkChecker != nil {
+			status.ExternalLinks = externalLinkChecker.getResults()
+		}
+		if assetChecker != nil {
+			status.Assets = assetChecker.getResults()
+			status.AssetMaxSizes = assetMaxSizes(assetsConf)
+		}
+		if thr != nil && baseURL != nil {
+			status.ScrapeSpeedLimit = thr.limit(baseURL.Host)
+			status.ScrapeCrawlDelay = thr.crawlDelay
+			status.ScrapeBackoff = thr.backoff(baseURL.Host)
+		}
+		status.Paused = paused
+		status.Halted = halted
+		status.NextRun = nextRun
+		status.History = historyStats()
+		return status
+	}
+
+	// the loop only wakes up without an event, when something is due
+	applyControl := func(c control) {
+		switch c {
+		case controlPause:
+			paused = true
+		case controlContinue:
+			paused = false
+			halted = false
+		case controlHalt:
+			halted = true
+			jobs = map[string]bool{}
+			jobsDirty = false
+			if front != nil {
+				resetFrontier()
+			}
 		}
 	}
 
+	startWalk := func(st start) {
+		// the previous walk is over
+		cancelWalk()
+		drain()
+		if chanLoopComplete != nil {
+			// nobody should wait for its loops
+			close(chanLoopComplete)
+			chanLoopComplete = nil
+		}
+		nextWalkCtx, cancelNextWalk := context.WithCancel(st.ctx)
+		walkCtx, cancelWalk = nextWalkCtx, cancelNextWalk
+		walkDone = st.ctx.Done()
+		paused = false
+		halted = false
+		robotsGroup = nil
+		robotsSitemaps = nil
+		groupHeader = st.conf.GroupHeader
+		concurrency = st.conf.Concurrency
+		scrapeFunc = st.scrapeFunc
+		validationFunc = st.validationFunc
+		linkListFilterFunc = st.linkListFilterFunc
+		ll.ignorePathPrefixes = st.conf.Ignore
+		ll.depth = st.conf.Depth
+		ll.paging = st.conf.Paging
+		groupValidator = st.groupValidator
+		ll.includePathPrefixes = st.conf.Target.Paths
+		ignoreRobots = st.conf.IgnoreRobots
+		once = st.conf.Once
+		retry = retryPolicy(st.conf.Retry)
+		ll.ignoreQueriesWith = st.conf.IgnoreQueriesWith
+		ll.ignoreAllQueries = st.conf.IgnoreAllQueries
+		ll.normalizer = st.normalizer
+		ll.rules = st.rules
+		budget = st.conf.Budget
+		priority = st.jobPriority
+		depthFirst = st.conf.Priority.DepthFirst
+		frontierConf = st.conf.Frontier
+		explainLinks = st.conf.ExplainLinks
+		scrapeResultModifierFunc = st.scrapeResultModifierFunc
+		if store != nil && store != st.store {
+			closeStore()
+		}
+		store = st.store
+		sitemapConf = st.conf.Sitemap
+		forbidden = st.forbidden
+		history = st.history
+		sched = st.schedule
+		// new certificates, proxies or timeouts need new clients
+		httpChanged := cp == nil || !cp.settings.equals(st.httpClientSettings)
+		// other credentials need fresh sessions
+		authChanged := cp == nil || !cp.auth.equals(st.auth)
+		if externalLinkChecker != nil && (httpChanged || authChanged || !reflect.DeepEqual(externalLinksConf, st.conf.ExternalLinks)) {
+			externalLinkChecker.stop()
+			externalLinkChecker = nil
+		}
+		externalLinksConf = st.conf.ExternalLinks
+		if externalLinkChecker == nil && externalLinksConf.Check {
+			externalLinkChecker = newLinkChecker(st.conf.Agent, st.httpClientSettings, st.auth, externalLinkCheckerOptions(externalLinksConf))
+		}
+		if assetChecker != nil && (httpChanged || authChanged || !reflect.DeepEqual(assetsConf, st.conf.Assets)) {
+			assetChecker.stop()
+			assetChecker = nil
+		}
+		assetsConf = st.conf.Assets
+		if assetChecker == nil && assetsConf.Check {
+			assetChecker = newLinkChecker(st.conf.Agent, st.httpClientSettings, st.auth, assetCheckerOptions(assetsConf))
+		}
+
+		// a form login needs cookie jars
+		useCookies := st.conf.UseCookies || !st.conf.Auth.Login.Empty()
+		if httpChanged || authChanged || cp.agent != st.conf.Agent || cp.concurrency != st.conf.Concurrency || cp.useCookies != useCookies {
+			if cp != nil {
+				cp.stop()
+			}
+			cp = newClientPool(st.conf.Concurrency, st.conf.Agent, useCookies, st.httpClientSettings, st.auth)
+			cp.start(w.chanResult)
+		}
+
+		var errStart error
+		startU, errParseStartU := url.Parse(st.conf.Target.BaseURL)
+		if errParseStartU != nil {
+			errStart = errParseStartU
+		}
+		if errStart == nil && st.auth != nil {
+			errStart = st.auth.loginPool(walkCtx, cp)
+		}
+		if errStart == nil && !ignoreRobots {
+			robotsData, errRobotsGroup := getRobotsData(cp.clients[0], st.conf.Target.BaseURL)
+			if errRobotsGroup == nil {
+				robotsSitemaps = robotsData.Sitemaps
+				robotsGroup = robotsData.FindGroup(st.conf.Agent)
+				robotForbiddenPath := []string{}
+				for _, p := range st.conf.Target.Paths {
+					if !robotsGroup.Test(p) {
+						robotForbiddenPath = append(robotForbiddenPath, p)
+					}
+				}
+				if len(robotForbiddenPath) > 0 {
+					errStart = errors.New("robots.txt does not allow access to the following path (you can either ignore robots or try as a different user agent): " + strings.Join(robotForbiddenPath, ", "))
+				}
+			} else {
+				errStart = errRobotsGroup
+			}
+		}
+		if errStart == nil && ignoreRobots && sitemapConf.Seed {
+			// we still want to know about the sitemaps
+			robotsData, errRobotsData := getRobotsData(cp.clients[0], st.conf.Target.BaseURL)
+			if errRobotsData == nil {
+				robotsSitemaps = robotsData.Sitemaps
+			}
+		}
+		crawlDelay := time.Duration(0)
+		if robotsGroup != nil {
+			crawlDelay = robotsGroup.CrawlDelay
+		}
+		thr = newThrottle(st.conf.Politeness, crawlDelay)
+		if st.conf.Baseline != "" {
+			baseline, errBaseline := LoadSnapshot(st.conf.Baseline)
+			if errBaseline != nil {
+				fmt.Println("could not load baseline", st.conf.Baseline, errBaseline)
+			} else if baseline != nil {
+				previous = baseline
+			}
+		} else if store != nil && previous == nil {
+			snapshot, errSnapshot := store.LoadSnapshot()
+			if errSnapshot != nil {
+				fmt.Println("could not load snapshot", errSnapshot)
+			} else if snapshot != nil {
+				previous = snapshot
+			}
+		}
+		if errStart == nil {
+			var resumeFrom *Checkpoint
+			if store != nil && st.conf.Resume {
+				loadedCheckpoint, errLoad := store.Load()
+				if errLoad != nil {
+					fmt.Println("could not load checkpoint, starting from scratch", errLoad)
+				} else if loadedCheckpoint != nil && loadedCheckpoint.BaseURL == startU.String() {
+					resumeFrom = loadedCheckpoint
+				}
+			}
+			if resumeFrom != nil {
+				resume(startU, st.conf.Target.Paths, resumeFrom)
+			} else {
+				restart(startU, st.conf.Target.Paths, nil)
+			}
+			chanLoopComplete = make(chan vo.Status)
+			w.chanStarted <- started{
+				Err:              errStart,
+				ChanLoopComplete: chanLoopComplete,
+			}
+		} else {
+			w.chanStarted <- started{
+				Err: errStart,
+			}
+		}
+
+	}
+
+	wakeUpTimer := time.NewTimer(time.Hour)
+	defer wakeUpTimer.Stop()
 	for {
-		if scrapeLoopStarted {
-			progressGaugeComplete.Set(float64(len(results)))
-			progressGaugeOpen.Set(float64(len(jobs)))
-			if len(jobs) > 0 {
-			JobLoop:
+		if jobsDirty && time.Since(lastCheckpoint) > checkpointInterval {
+			checkpoint()
+		}
+		// when to look at the jobs again, 0 means there is nothing to wait for
+		wakeUp := time.Duration(0)
+		wakeUpIn := func(wait time.Duration) {
+			if wakeUp == 0 || wait < wakeUp {
+				wakeUp = wait
+			}
+		}
+		if jobsDirty && store != nil {
+			wakeUpIn(time.Until(lastCheckpoint.Add(checkpointInterval)) + time.Millisecond)
+		}
+		if !nextRun.IsZero() {
+			if wait := time.Until(nextRun); wait > 0 {
+				wakeUpIn(wait)
+			} else {
+				fmt.Println("starting scheduled loop", baseURL, paths)
+				restart(baseURL, paths, nil)
+			}
+		}
+		if scrapeLoopStarted && !halted && nextRun.IsZero() && budgetExhausted == "" {
+			if budget.MaxPages > 0 && len(results)+running >= budget.MaxPages {
+				budgetExhausted = "max pages " + strconv.Itoa(budget.MaxPages)
+			} else if budget.MaxDuration > 0 && time.Since(loopStart) >= budget.MaxDuration {
+				budgetExhausted = "max duration " + budget.MaxDuration.String()
+			}
+			if budgetExhausted != "" {
+				fmt.Println("budget exhausted", budgetExhausted, baseURL, paths)
+			} else if budget.MaxDuration > 0 {
+				wakeUpIn(time.Until(loopStart.Add(budget.MaxDuration)))
+			}
+		}
+		if budgetExhausted != "" {
+			// running scrapes complete the loop
+			for jobURL, jobActive := range jobs {
+				if !jobActive {
+					delete(jobs, jobURL)
+					budgetDropped++
+				}
+			}
+			if front.size() > 0 {
+				resetFrontier()
+			}
+		}
+		if scrapeLoopStarted && !paused && !halted && nextRun.IsZero() {
+			m.progressGaugeComplete.Set(float64(len(results)))
+			m.progressGaugeOpen.Set(float64(len(jobs)))
+			if front.size() == 0 && len(jobs) > running {
+				// the spill file is gone, the jobs are not
+				fmt.Println("requeueing lost jobs", baseURL, paths)
+				lostJobs := []string{}
 				for jobURL, jobActive := range jobs {
-					if running >= concurrency {
-						// concurrency limit
-						break
-					}
 					if !jobActive {
-						for _, poolClient := range cp.clients {
-							if !poolClient.busy {
-								running++
-								jobs[jobURL] = true
-								poolClient.busy = true
-								go scrape(poolClient, jobURL, baseURL, groupHeader, scrapeFunc, validationFunc, groupValidator, w.chanResult)
-								continue JobLoop
-							}
-						}
-						break JobLoop
+						lostJobs = append(lostJobs, jobURL)
+					}
+				}
+				sort.Strings(lostJobs)
+				for _, jobURL := range lostJobs {
+					queueJob(jobURL)
+				}
+			}
+			now := time.Now()
+			for running < concurrency && front.size() > 0 {
+				if budget.MaxPages > 0 && len(results)+running >= budget.MaxPages {
+					break
+				}
+				job := front.pop()
+				if job == nil {
+					break
+				}
+				if jobActive, ok := jobs[job.url]; !ok || jobActive {
+					// dropped or started in the meantime
+					continue
+				}
+				if len(budget.MaxPagesPerGroup) > 0 {
+					group, guessed := "default", true
+					if groupHeader != "" {
+						group, guessed = groupGuess.guess(job.url)
+					}
+					if maxGroupPages, ok := budget.MaxPagesPerGroup[group]; guessed && ok && groupPages[group] >= maxGroupPages {
+						// do not fetch, what will be dropped
+						delete(jobs, job.url)
+						jobsDirty = true
+						overBudget[job.url] = true
+						budgetDropped++
+						continue
 					}
 				}
+				jobHost := baseURL.Host
+				if jobU, errParseJobU := url.Parse(job.url); errParseJobU == nil {
+					jobHost = jobU.Host
+				}
+				if wait := thr.wait(jobHost, now); wait > 0 {
+					// be polite, all jobs are on the same host
+					wakeUpIn(wait)
+					front.requeue(job)
+					break
+				}
+				running++
+				jobs[job.url] = true
+				thr.take(jobHost, now)
+				// there is a worker for every running job
+				cp.chanJobs <- scrapeJob{
+					ctx:            walkCtx,
+					targetURL:      job.url,
+					baseURL:        baseURL,
+					groupHeader:    groupHeader,
+					scrapeFunc:     scrapeFunc,
+					validationFunc: validationFunc,
+					groupValidator: groupValidator,
+					retry:          retry,
+					thr:            thr,
+				}
 			}
 		}
 
 		// time to restart
-		if results != nil && len(jobs) == 0 && running == 0 && baseURL != nil {
-			fmt.Println("restarting", baseURL, paths)
-			w.CompleteStatus = &vo.Status{
-				Results: results,
-				Jobs:    jobs,
+		if results != nil && len(jobs) == 0 && running == 0 && !sitemapPending && baseURL != nil && !halted && nextRun.IsZero() {
+			completeStatus := &vo.Status{
+				Results:       results,
+				Jobs:          jobs,
+				Sitemap:       sitemap,
+				LinkVariants:  linkVariants,
+				LinkDecisions: ll.decisions,
+				Hops:          hops,
+				Budget:        budgetExhausted,
+				BudgetDropped: budgetDropped,
 			}
+			if externalLinkChecker != nil {
+				completeStatus.ExternalLinks = externalLinkChecker.getResults()
+			}
+			if assetChecker != nil {
+				completeStatus.Assets = assetChecker.getResults()
+				completeStatus.AssetMaxSizes = assetMaxSizes(assetsConf)
+			}
+			completeStatus.Previous = previous
+			nextPrevious := *completeStatus
+			nextPrevious.Previous = nil
+			previous = &nextPrevious
+			if history != nil {
+				errHistory := history.Add(*completeStatus)
+				if errHistory != nil {
+					fmt.Println("could not add to history", errHistory)
+				}
+				completeStatus.History = historyStats()
+			}
+			if store != nil {
+				errSnapshot := store.SaveSnapshot(*completeStatus)
+				if errSnapshot != nil {
+					fmt.Println("could not save snapshot", errSnapshot)
+				}
+			}
+			w.setCompleteStatus(completeStatus)
 			if chanLoopComplete != nil {
 				go reportSchemaValidationMetrics(
-					*w.CompleteStatus,
+					*completeStatus,
 					paths,
-					trackValidationPenalties,
-					trackValidationScore,
+					m.trackValidationPenalty,
+					m.trackValidationScore,
 				)
-				chanLoopComplete <- *w.CompleteStatus
-			}
-			restart(baseURL, paths)
-		}
-
-		select {
-		case <-time.After(time.Millisecond * 1000):
-			// make sure we do not get stuck
-		case st := <-w.chanStart:
-			robotsGroup = nil
-			groupHeader = st.conf.GroupHeader
-			concurrency = st.conf.Concurrency
-			scrapeFunc = st.scrapeFunc
-			validationFunc = st.validationFunc
-			linkListFilterFunc = st.linkListFilterFunc
-			ll.ignorePathPrefixes = st.conf.Ignore
-			ll.depth = st.conf.Depth
-			ll.paging = st.conf.Paging
-			groupValidator = st.groupValidator
-			ll.includePathPrefixes = st.conf.Target.Paths
-			ignoreRobots = st.conf.IgnoreRobots
-			ll.ignoreQueriesWith = st.conf.IgnoreQueriesWith
-			ll.ignoreAllQueries = st.conf.IgnoreAllQueries
-			scrapeResultModifierFunc = st.scrapeResultModifierFunc
-
-			if cp == nil || cp.agent != st.conf.Agent || cp.concurrency != st.conf.Concurrency || cp.useCookies != st.conf.UseCookies {
-				cp = newClientPool(st.conf.Concurrency, st.conf.Agent, st.conf.UseCookies)
-			}
-
-			var errStart error
-			startU, errParseStartU := url.Parse(st.conf.Target.BaseURL)
-			if errParseStartU != nil {
-				errStart = errParseStartU
-			}
-			if errStart == nil && !ignoreRobots {
-				robotsData, errRobotsGroup := getRobotsData(st.conf.Target.BaseURL)
-				if errRobotsGroup == nil {
-					robotsGroup = robotsData.FindGroup(st.conf.Agent)
-					robotForbiddenPath := []string{}
-					for _, p := range st.conf.Target.Paths {
-						if !robotsGroup.Test(p) {
-							robotForbiddenPath = append(robotForbiddenPath, p)
-						}
-					}
-					if len(robotForbiddenPath) > 0 {
-						errStart = errors.New("robots.txt does not allow access to the following path (you can either ignore robots or try as a different user agent): " + strings.Join(robotForbiddenPath, ", "))
+				replaced := false
+			LoopComplete:
+				for {
+					select {
+					case chanLoopComplete <- *completeStatus:
+						break LoopComplete
+					case c := <-w.chanControl:
+						applyControl(c)
+					case st := <-w.chanStart:
+						// nobody took the loop, the new walk replaces it
+						startWalk(st)
+						replaced = true
+						break LoopComplete
+					case <-w.chanStatus:
+						w.chanStatus <- getStatus()
+					case <-walkDone:
+						fmt.Println("walk cancelled", baseURL, paths)
+						return
+					case <-w.chanStop:
+						shutdown()
+						w.chanStop <- getStatus()
+						return
 					}
-				} else {
-					errStart = errRobotsGroup
 				}
-			}
-			if errStart == nil {
-				restart(startU, st.conf.Target.Paths)
-				chanLoopComplete = make(chan vo.Status)
-				w.chanStarted <- started{
-					Err:              errStart,
-					ChanLoopComplete: chanLoopComplete,
+				if replaced {
+					continue
 				}
-			} else {
-				chanLoopComplete = nil
-				w.chanStarted <- started{
-					Err: errStart,
+			}
+			if once {
+				fmt.Println("done", baseURL, paths)
+				if store != nil {
+					errReset := store.Reset()
+					if errReset != nil {
+						fmt.Println("could not reset store", errReset)
+					}
 				}
+				// shutdown closes chanLoopComplete
+				return
 			}
+			if sched != nil {
+				// the complete loop stays in the status, until the next one starts
+				nextRun = sched.next(loopStart, time.Now())
+				fmt.Println("next loop of", baseURL, paths, "at", nextRun)
+				continue
+			}
+			fmt.Println("restarting", baseURL, paths)
+			restart(baseURL, paths, nil)
+			// nothing wakes us up for the new jobs
+			continue
+		}
 
+		if !wakeUpTimer.Stop() {
+			select {
+			case <-wakeUpTimer.C:
+			default:
+			}
+		}
+		var chanWakeUp <-chan time.Time
+		if wakeUp > 0 {
+			wakeUpTimer.Reset(wakeUp)
+			chanWakeUp = wakeUpTimer.C
+		}
+		select {
+		case <-chanWakeUp:
+			// something is due
+		case c := <-w.chanControl:
+			applyControl(c)
+		case <-walkDone:
+			fmt.Println("walk cancelled", baseURL, paths)
+			return
+		case st := <-w.chanStart:
+			startWalk(st)
 		case <-w.chanStatus:
 			w.chanStatus <- getStatus()
 		case <-w.chanStop:
+			shutdown()
 			w.chanStop <- getStatus()
 			return
+		case seed := <-chanSitemap:
+			if seed.loop == loopID {
+				addSitemap(seed)
+			}
 		case scanResult := <-w.chanResult:
 			running--
+			if halted {
+				// nobody is waiting for that one
+				continue
+			}
 			delete(jobs, scanResult.result.TargetURL)
+			// links have to match the keys of the results
+			scanResult.result.NormalizedLinks = ll.normalizer.normalizeLinks(baseURL, scanResult.result.Links, linkVariants)
+			scanResult.result.FragmentLinks = ll.normalizer.normalizeFragmentLinks(scanResult.result.FragmentLinks)
 			if scrapeResultModifierFunc != nil {
 				modifiedScrapeResult, errModify := scrapeResultModifierFunc(scanResult.result)
 				if errModify == nil {
@@ -321,49 +1094,60 @@ func (w *Walker) scrapeloop() {
 					fmt.Println("cound not modify scrape result", errModify)
 				}
 			}
-			scanResult.poolClient.busy = false
 			scanResult.result.Time = time.Now()
 			statusCodeAsString := strconv.Itoa(scanResult.result.Code)
-			counterVecStatus.WithLabelValues(statusCodeAsString).Inc()
-			results[scanResult.result.TargetURL] = scanResult.result
-
-			summaryVec.WithLabelValues(scanResult.result.Group).Observe(scanResult.result.Duration.Seconds())
-			counterVec.WithLabelValues(scanResult.result.Group, statusCodeAsString).Inc()
-			totalCounter.Inc()
-
-			var linksToScrape vo.LinkList
-
+			m.counterVecStatus.WithLabelValues(statusCodeAsString).Inc()
+			if forbidden != nil {
+				scanResult.result.Validations = append(
+					scanResult.result.Validations,
+					forbidden.validate(scanResult.result, baseURL, m.trackForbiddenLink)...,
+				)
+			}
+			scanResult.result.Discovery = discovery[scanResult.result.TargetURL]
+			if scanResult.result.Discovery == "" {
+				scanResult.result.Discovery = vo.DiscoveryLinks
+			}
+			scanResult.result.Hops = -1
+			if hop, ok := hops[scanResult.result.TargetURL]; ok {
+				scanResult.result.Hops = hop
+			}
+			groupPages[scanResult.result.Group]++
+			groupGuess.learn(scanResult.result.TargetURL, scanResult.result.Group)
+			if maxGroupPages, ok := budget.MaxPagesPerGroup[scanResult.result.Group]; ok && groupPages[scanResult.result.Group] > maxGroupPages {
+				overBudget[scanResult.result.TargetURL] = true
+				budgetDropped++
+				continue
+			}
+			linksToScrape := linksToFollow(scanResult.result, scanResult.doc, scanResult.docURL)
 			if linkListFilterFunc != nil {
-				if scanResult.result.Error != "" {
-					fmt.Println("there was an error", scanResult.result.Error)
-				} else if scanResult.doc != nil {
-					linksToScrapeFromFromLilterFunc, errFilterLinkList := linkListFilterFunc(baseURL, scanResult.docURL, scanResult.doc)
-					if errFilterLinkList != nil {
-						fmt.Println("aua", errFilterLinkList)
-					}
-					linksToScrape = linksToScrapeFromFromLilterFunc
-				}
-			} else if ignoreRobots || !strings.Contains(scanResult.result.Structure.Robots, "nofollow") {
-				linkNextNormalized := ""
-				linkPrevNormalized := ""
-				// should we follow the links
-				linkNextNormalizedURL, errNormalizeNext := NormalizeLink(baseURL, scanResult.result.Structure.LinkNext)
-				if errNormalizeNext == nil {
-					linkNextNormalized = linkNextNormalizedURL.String()
-				}
-				linkPrevNormalizedURL, errNormalizedPrev := NormalizeLink(baseURL, scanResult.result.Structure.LinkPrev)
-				if errNormalizedPrev == nil {
-					linkPrevNormalized = linkPrevNormalizedURL.String()
+				scanResult.result.FilteredLinks = linksToScrape
+			}
+			results[scanResult.result.TargetURL] = scanResult.result
+			if resultU, errParseResultU := url.Parse(scanResult.result.TargetURL); errParseResultU == nil {
+				thr.feedback(resultU.Host, scanResult.result.Code, scanResult.result.Duration)
+			}
+			if store != nil {
+				errSave := store.SaveResult(scanResult.result)
+				if errSave != nil {
+					fmt.Println("could not save result", errSave)
 				}
+			}
+
+			m.summaryVec.WithLabelValues(scanResult.result.Group).Observe(scanResult.result.Duration.Seconds())
+			m.trackTiming(scanResult.result.Group, scanResult.result.Timing)
+			m.counterVec.WithLabelValues(scanResult.result.Group, statusCodeAsString).Inc()
+			m.totalCounter.Inc()
 
-				linksToScrape = filterScrapeLinks(scanResult.result.Links, baseURL, linkNextNormalized, linkPrevNormalized, ll, robotsGroup)
+			addJobs(linksToScrape, vo.DiscoveryLinks, nextHop(scanResult.result))
+			if externalLinkChecker != nil {
+				externalLinkChecker.check(walkCtx, externalLinks(scanResult.result.Links, baseURL))
 			}
-			for linkToScrape := range linksToScrape {
-				_, existingResultOK := results[linkToScrape]
-				_, existingJobOK := jobs[linkToScrape]
-				if !existingResultOK && !existingJobOK {
-					jobs[linkToScrape] = false
+			if assetChecker != nil && len(scanResult.result.Assets) > 0 {
+				assets := make([]string, 0, len(scanResult.result.Assets))
+				for asset := range scanResult.result.Assets {
+					assets = append(assets, asset)
 				}
+				assetChecker.check(walkCtx, assets)
 			}
 		}
 	}
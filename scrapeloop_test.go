@@ -0,0 +1,72 @@
+package walker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/foomo/walker/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// newBenchmarkSite serves a tree of pages, every page links to its children and to the start page
+func newBenchmarkSite(pages, children int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, errN := 0, error(nil)
+		if r.URL.Path != "/" {
+			n, errN = strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/p/"))
+		}
+		if errN != nil || n >= pages {
+			http.NotFound(w, r)
+			return
+		}
+		html := strings.Builder{}
+		html.WriteString(`<html><head><title>page ` + strconv.Itoa(n) + `</title></head><body><a href="/">home</a>`)
+		for child := n*children + 1; child <= n*children+children && child < pages; child++ {
+			html.WriteString(`<a href="/p/` + strconv.Itoa(child) + `">page</a>`)
+		}
+		html.WriteString("</body></html>")
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(html.String()))
+	}))
+}
+
+func benchmarkWalk(b *testing.B, pages, concurrency int) {
+	testServer := newBenchmarkSite(pages, 8)
+	defer testServer.Close()
+	b.ResetTimer()
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		w, errNew := New(WithRegisterer(prometheus.NewRegistry()))
+		if errNew != nil {
+			b.Fatal(errNew)
+		}
+		chanStatus, errWalk := w.WalkContext(context.Background(), &config.Config{
+			Target:       config.Target{BaseURL: testServer.URL, Paths: []string{"/"}},
+			IgnoreRobots: true,
+			Once:         true,
+			Concurrency:  concurrency,
+		})
+		if errWalk != nil {
+			b.Fatal(errWalk)
+		}
+		status := <-chanStatus
+		if len(status.Results) != pages {
+			b.Fatal("expected", pages, "results, got", len(status.Results))
+		}
+		w.Stop()
+	}
+	b.ReportMetric(float64(pages*b.N)/time.Since(start).Seconds(), "pages/s")
+}
+
+func BenchmarkWalk20k(b *testing.B) {
+	benchmarkWalk(b, 20000, 16)
+}
+
+func BenchmarkWalk50k(b *testing.B) {
+	benchmarkWalk(b, 50000, 32)
+}
@@ -1,9 +1,11 @@
 package walker
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/foomo/walker/config"
@@ -13,8 +15,20 @@ import (
 type Service struct {
 	Walker *Walker
 	// targetURL string
+	mutex            sync.Mutex
+	ctx              context.Context
+	conf             *config.Config
+	walkOptions      []WalkOption
+	chanLoopComplete chan vo.Status
+	// counts the walks, only the current one may close chanLoopComplete
+	walks int
+	// chanLoopComplete is closed, the service can not walk again
+	closed bool
 }
 
+// ErrServiceDone is returned, when reconfiguring a service, whose walk is over
+var ErrServiceDone = errors.New("service is done")
+
 func NewService(
 	conf *config.Config,
 	linkListFilter LinkListFilterFunc,
@@ -22,16 +36,98 @@ func NewService(
 	validationFunc ValidationFunc,
 	scrapeResultModifierFunc ScrapeResultModifierFunc,
 ) (s *Service, chanLoopComplete chan vo.Status, err error) {
-	w := NewWalker()
-	chanLoopComplete, errWalk := w.Walk(conf, linkListFilter, scrapeFunc, validationFunc, scrapeResultModifierFunc)
+	return NewServiceContext(
+		context.Background(),
+		conf,
+		WithLinkListFilter(linkListFilter),
+		WithScrapeFunc(scrapeFunc),
+		WithValidationFunc(validationFunc),
+		WithScrapeResultModifier(scrapeResultModifierFunc),
+	)
+}
+
+// NewServiceContext starts walking, cancelling ctx terminates the walker and closes chanLoopComplete
+func NewServiceContext(
+	ctx context.Context,
+	conf *config.Config,
+	opts ...WalkOption,
+) (s *Service, chanLoopComplete chan vo.Status, err error) {
+	return NewServiceWithWalker(ctx, NewWalker(), conf, opts...)
+}
+
+// NewServiceWithWalker is NewServiceContext for a walker with its own name, store or registerer
+func NewServiceWithWalker(
+	ctx context.Context,
+	w *Walker,
+	conf *config.Config,
+	opts ...WalkOption,
+) (s *Service, chanLoopComplete chan vo.Status, err error) {
+	s = &Service{
+		Walker:           w,
+		ctx:              ctx,
+		walkOptions:      opts,
+		chanLoopComplete: make(chan vo.Status),
+		// targetURL: conf.Target,
+	}
+	errWalk := s.Reconfigure(conf)
 	if errWalk != nil {
 		return nil, nil, errWalk
 	}
-	s = &Service{
-		Walker: w,
-		// targetURL: conf.Target,
+	return s, s.chanLoopComplete, nil
+}
+
+// Reconfigure restarts the walk with a new config, loop completions keep coming through the same channel
+func (s *Service) Reconfigure(conf *config.Config) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(conf.Targets) > 0 {
+		return errors.New("a service walks a single target, reconfigure the targets one by one")
 	}
-	return
+	if s.closed {
+		return ErrServiceDone
+	}
+	if conf.Name == "" && s.conf != nil {
+		conf.Name = s.conf.Name
+	}
+	// the walker validates the config, before it replaces the previous walk and closes its loop complete channel
+	chanWalkLoopComplete, errWalk := s.Walker.WalkContext(s.ctx, conf, s.walkOptions...)
+	if errWalk != nil {
+		// an invalid config leaves the previous walk running, a failed start ends it and its forwarder closes chanLoopComplete
+		return errWalk
+	}
+	s.walks++
+	s.conf = conf
+	go s.forward(chanWalkLoopComplete, s.walks)
+	return nil
+}
+
+// forward the loops of a walk, until it is over
+func (s *Service) forward(chanWalkLoopComplete chan vo.Status, walk int) {
+	for status := range chanWalkLoopComplete {
+		select {
+		case s.chanLoopComplete <- status:
+		case <-s.ctx.Done():
+		}
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if walk == s.walks {
+		// once mode, cancelled or a failed restart
+		close(s.chanLoopComplete)
+		s.closed = true
+	}
+}
+
+// Restart the walk with the current config
+func (s *Service) Restart() error {
+	return s.Reconfigure(s.GetConfig())
+}
+
+// GetConfig returns the config of the current walk
+func (s *Service) GetConfig() *config.Config {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.conf
 }
 
 func filter(resultMap map[string]vo.ScrapeResult, filterChain filterChain) {
@@ -146,8 +242,10 @@ func (s *Service) GetResults(
 	for index, url := range urls {
 		results[index] = resultMap[url]
 	}
-	// paging is missing
-	numPages = len(results) / pageSize
+	if pageSize < 1 {
+		pageSize = len(results) + 1
+	}
+	numPages = (len(results) + pageSize - 1) / pageSize
 	min := 0
 	max := len(results)
 	start := page * pageSize
@@ -159,9 +257,10 @@ func (s *Service) GetResults(
 	if end > max {
 		end = max
 	}
-	fmt.Println("slice", start, end)
 	if end > start {
 		results = results[start:end]
+	} else {
+		results = []vo.ScrapeResult{}
 	}
 	return
 }
@@ -0,0 +1,123 @@
+package walker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/foomo/walker/config"
+	"github.com/foomo/walker/vo"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconfigure(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body></body></html>"))
+	}))
+	defer testServer.Close()
+	newConf := func() *config.Config {
+		return &config.Config{
+			Target:       config.Target{BaseURL: testServer.URL, Paths: []string{"/"}},
+			IgnoreRobots: true,
+			Concurrency:  1,
+			Schedule:     config.Schedule{Interval: time.Hour},
+		}
+	}
+
+	// a new walk closes the loop complete channel of the previous one
+	w, errNew := New(WithRegisterer(prometheus.NewRegistry()))
+	require.NoError(t, errNew)
+	defer w.Stop()
+	first, errFirst := w.WalkContext(context.Background(), newConf())
+	require.NoError(t, errFirst)
+	<-first
+	_, errSecond := w.WalkContext(context.Background(), newConf())
+	require.NoError(t, errSecond)
+	select {
+	case _, ok := <-first:
+		assert.False(t, ok)
+	case <-time.After(time.Second * 5):
+		t.Fatal("first loop complete channel is still open")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s, chanLoopComplete, errService := NewServiceWithWalker(ctx, NewWalker(), newConf())
+	require.NoError(t, errService)
+	<-chanLoopComplete
+	require.NoError(t, s.Reconfigure(newConf()))
+	require.NoError(t, s.Reconfigure(newConf()))
+	// the service keeps forwarding the loops of the current walk
+	select {
+	case _, ok := <-chanLoopComplete:
+		assert.True(t, ok)
+	case <-time.After(time.Second * 5):
+		t.Fatal("no loop after reconfiguring")
+	}
+
+	// an invalid config neither stops the current walk nor counts as a walk
+	walks := s.walks
+	currentConf := s.GetConfig()
+	invalidConf := newConf()
+	invalidConf.Schedule = config.Schedule{Cron: "not a cron"}
+	assert.Error(t, s.Reconfigure(invalidConf))
+	assert.Equal(t, walks, s.walks)
+	assert.Equal(t, currentConf, s.GetConfig())
+	require.NoError(t, s.Restart())
+	select {
+	case _, ok := <-chanLoopComplete:
+		assert.True(t, ok)
+	case <-time.After(time.Second * 5):
+		t.Fatal("no loop after a failed reconfiguration")
+	}
+
+	// forwarders of replaced walks exit without closing the channel of the service
+	chanReplaced := make(chan vo.Status)
+	forwarderDone := make(chan struct{})
+	replacedWalk := s.walks - 1
+	go func() {
+		s.forward(chanReplaced, replacedWalk)
+		close(forwarderDone)
+	}()
+	close(chanReplaced)
+	select {
+	case <-forwarderDone:
+	case <-time.After(time.Second * 5):
+		t.Fatal("forwarder did not exit")
+	}
+
+	cancel()
+	select {
+	case _, ok := <-chanLoopComplete:
+		assert.False(t, ok)
+	case <-time.After(time.Second * 5):
+		t.Fatal("service did not close its channel")
+	}
+}
+
+func TestReconfigureOnce(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body></body></html>"))
+	}))
+	defer testServer.Close()
+	w, errNew := New(WithRegisterer(prometheus.NewRegistry()))
+	require.NoError(t, errNew)
+	defer w.Stop()
+	s, chanLoopComplete, errService := NewServiceWithWalker(context.Background(), w, &config.Config{
+		Target:       config.Target{BaseURL: testServer.URL, Paths: []string{"/"}},
+		IgnoreRobots: true,
+		Concurrency:  1,
+		Once:         true,
+	})
+	require.NoError(t, errService)
+	for range chanLoopComplete {
+	}
+	// the channel is closed, a new walk would have nowhere to go
+	assert.Equal(t, ErrServiceDone, s.Restart())
+}
@@ -0,0 +1,131 @@
+package walker
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/foomo/walker/vo"
+)
+
+// do not get lost in nested sitemap indexes
+const maxSitemaps = 1000
+
+type sitemapURLSet struct {
+	URLs []struct {
+		Loc        string  `xml:"loc"`
+		LastMod    string  `xml:"lastmod"`
+		ChangeFreq string  `xml:"changefreq"`
+		Priority   float64 `xml:"priority"`
+	} `xml:"url"`
+}
+
+type sitemapIndex struct {
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+// getSitemapEntries loads all entries from the given sitemaps and the sitemap indexes they are pointing to
+func getSitemapEntries(ctx context.Context, pc *poolClient, sitemapURLs []string) (entries map[string]vo.SitemapEntry, err error) {
+	entries = map[string]vo.SitemapEntry{}
+	visited := map[string]bool{}
+	queue := append([]string{}, sitemapURLs...)
+	for len(queue) > 0 && len(visited) < maxSitemaps && ctx.Err() == nil {
+		sitemapURL := queue[0]
+		queue = queue[1:]
+		if visited[sitemapURL] {
+			continue
+		}
+		visited[sitemapURL] = true
+		urlSet, index, errLoad := loadSitemap(ctx, pc, sitemapURL)
+		if errLoad != nil {
+			err = errLoad
+			fmt.Println("could not load sitemap", sitemapURL, errLoad)
+			continue
+		}
+		if index != nil {
+			for _, s := range index.Sitemaps {
+				queue = append(queue, strings.TrimSpace(s.Loc))
+			}
+		}
+		if urlSet != nil {
+			for _, u := range urlSet.URLs {
+				loc := strings.TrimSpace(u.Loc)
+				if loc == "" {
+					continue
+				}
+				priority := u.Priority
+				if priority == 0 {
+					// default according to sitemaps.org
+					priority = 0.5
+				}
+				entries[loc] = vo.SitemapEntry{
+					Loc:        loc,
+					Sitemap:    sitemapURL,
+					LastMod:    strings.TrimSpace(u.LastMod),
+					ChangeFreq: strings.TrimSpace(u.ChangeFreq),
+					Priority:   priority,
+				}
+			}
+		}
+	}
+	if len(entries) > 0 {
+		// we got something
+		err = nil
+	}
+	return entries, err
+}
+
+func loadSitemap(ctx context.Context, pc *poolClient, sitemapURL string) (urlSet *sitemapURLSet, index *sitemapIndex, err error) {
+	req, errRequest := pc.newRequest(http.MethodGet, sitemapURL)
+	if errRequest != nil {
+		return nil, nil, errRequest
+	}
+	resp, errGet := pc.client.Do(req.WithContext(ctx))
+	if errGet != nil {
+		return nil, nil, errGet
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, errors.New("unexpected status: " + resp.Status)
+	}
+	var reader io.Reader = bufio.NewReader(resp.Body)
+	// sitemap.xml.gz is usually not served with a content encoding
+	magic, _ := reader.(*bufio.Reader).Peek(2)
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gzipReader, errGzip := gzip.NewReader(reader)
+		if errGzip != nil {
+			return nil, nil, errGzip
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+	decoder := xml.NewDecoder(reader)
+	for {
+		token, errToken := decoder.Token()
+		if errToken != nil {
+			return nil, nil, errToken
+		}
+		startElement, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch startElement.Name.Local {
+		case "urlset":
+			urlSet = &sitemapURLSet{}
+			return urlSet, nil, decoder.DecodeElement(urlSet, &startElement)
+		case "sitemapindex":
+			index = &sitemapIndex{}
+			return nil, index, decoder.DecodeElement(index, &startElement)
+		default:
+			return nil, nil, errors.New("unexpected sitemap root element: " + startElement.Name.Local)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package walker
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/foomo/walker/config"
+	"github.com/foomo/walker/vo"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSitemapEntries(t *testing.T) {
+	var testServer *httptest.Server
+	testServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<sitemap><loc>` + testServer.URL + `/sitemap-pages.xml</loc></sitemap>
+	<sitemap><loc>` + testServer.URL + `/sitemap-products.xml.gz</loc></sitemap>
+	<sitemap><loc>` + testServer.URL + `/sitemap-missing.xml</loc></sitemap>
+</sitemapindex>`))
+		case "/sitemap-pages.xml":
+			w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc>` + testServer.URL + `/</loc><priority>1.0</priority></url>
+	<url>
+		<loc>
+			` + testServer.URL + `/about
+		</loc>
+		<lastmod>2020-01-01</lastmod>
+	</url>
+</urlset>`))
+		case "/sitemap-products.xml.gz":
+			buf := &bytes.Buffer{}
+			gzipWriter := gzip.NewWriter(buf)
+			gzipWriter.Write([]byte(`<urlset><url><loc>` + testServer.URL + `/p/1</loc></url></urlset>`))
+			gzipWriter.Close()
+			w.Header().Set("Content-Type", "application/x-gzip")
+			w.Write(buf.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer testServer.Close()
+	cp := newClientPool(1, "test", false, testHTTPClientSettings(config.HTTP{}), nil)
+	entries, errEntries := getSitemapEntries(context.Background(), cp.clients[0], []string{testServer.URL + "/sitemap.xml"})
+	assert.NoError(t, errEntries)
+	assert.Len(t, entries, 3)
+	assert.Equal(t, 1.0, entries[testServer.URL+"/"].Priority)
+	assert.Equal(t, 0.5, entries[testServer.URL+"/about"].Priority)
+	assert.Equal(t, "2020-01-01", entries[testServer.URL+"/about"].LastMod)
+	assert.Equal(t, testServer.URL+"/sitemap-products.xml.gz", entries[testServer.URL+"/p/1"].Sitemap)
+}
+
+func TestDiscoveryAdd(t *testing.T) {
+	d := vo.Discovery("")
+	d = d.Add(vo.DiscoverySitemap)
+	assert.Equal(t, vo.DiscoverySitemap, d)
+	assert.Equal(t, vo.DiscoverySitemap, d.Add(vo.DiscoverySitemap))
+	assert.Equal(t, vo.DiscoveryBoth, d.Add(vo.DiscoveryLinks))
+	assert.Equal(t, vo.DiscoveryBoth, vo.DiscoveryBoth.Add(vo.DiscoveryLinks))
+}
+
+func TestSeedSitemapInBackground(t *testing.T) {
+	release := make(chan struct{})
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			<-release
+			w.Write([]byte(`<urlset><url><loc>http://` + r.Host + `/hidden</loc></url></urlset>`))
+		case "/", "/hidden":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><body></body></html>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer testServer.Close()
+	defer func() {
+		select {
+		case <-release:
+		default:
+			close(release)
+		}
+	}()
+
+	w, errNew := New(WithRegisterer(prometheus.NewRegistry()))
+	require.NoError(t, errNew)
+	defer w.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	chanStatus, errWalk := w.WalkContext(ctx, &config.Config{
+		Target:       config.Target{BaseURL: testServer.URL, Paths: []string{"/"}},
+		IgnoreRobots: true,
+		Once:         true,
+		Concurrency:  1,
+		Sitemap:      config.Sitemap{Seed: true},
+	})
+	require.NoError(t, errWalk)
+
+	// the loop answers and does not complete, while the sitemap is loading
+	chanGotStatus := make(chan vo.Status)
+	go func() {
+		chanGotStatus <- w.GetStatus()
+	}()
+	select {
+	case status := <-chanGotStatus:
+		assert.Nil(t, status.Sitemap)
+	case <-time.After(time.Second * 5):
+		t.Fatal("status blocked by the sitemap")
+	}
+	select {
+	case <-chanStatus:
+		t.Fatal("loop completed without the sitemap")
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	close(release)
+	select {
+	case status := <-chanStatus:
+		assert.Len(t, status.Results, 2)
+		assert.Equal(t, vo.DiscoverySitemap, status.Results[testServer.URL+"/hidden"].Discovery)
+	case <-ctx.Done():
+		t.Fatal("walk did not complete")
+	}
+}
@@ -0,0 +1,41 @@
+package walker
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+
+	"github.com/foomo/walker/vo"
+)
+
+// SaveSnapshot writes a complete status, so that the next crawl can be compared to it
+func SaveSnapshot(filename string, status vo.Status) error {
+	// the previous one does not belong into a snapshot
+	status.Previous = nil
+	statusBytes, errMarshal := json.Marshal(status)
+	if errMarshal != nil {
+		return errMarshal
+	}
+	errWrite := ioutil.WriteFile(filename+".tmp", statusBytes, 0644)
+	if errWrite != nil {
+		return errWrite
+	}
+	return os.Rename(filename+".tmp", filename)
+}
+
+// LoadSnapshot reads a status written by SaveSnapshot, nil if there is none
+func LoadSnapshot(filename string) (status *vo.Status, err error) {
+	statusBytes, errRead := ioutil.ReadFile(filename)
+	if os.IsNotExist(errRead) {
+		return nil, nil
+	}
+	if errRead != nil {
+		return nil, errRead
+	}
+	status = &vo.Status{}
+	errUnmarshal := json.Unmarshal(statusBytes, status)
+	if errUnmarshal != nil {
+		return nil, errUnmarshal
+	}
+	return status, nil
+}
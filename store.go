@@ -0,0 +1,186 @@
+package walker
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/foomo/walker/vo"
+)
+
+// Checkpoint is the persisted state of an interrupted scrape loop
+type Checkpoint struct {
+	BaseURL string
+	Paths   []string
+	Jobs    map[string]bool
+	Results map[string]vo.ScrapeResult
+}
+
+// Store persists the frontier and the results of a scrape loop, so that it can be resumed
+type Store interface {
+	// Load the last checkpoint, nil if there is none
+	Load() (checkpoint *Checkpoint, err error)
+	// SaveJobs replaces the persisted frontier
+	SaveJobs(baseURL string, paths []string, jobs map[string]bool) error
+	// SaveResult adds a result to the current loop
+	SaveResult(result vo.ScrapeResult) error
+	// Reset drops the state of the last loop, but keeps the snapshot
+	Reset() error
+	// SaveSnapshot keeps the status of a complete loop for the next diff
+	SaveSnapshot(status vo.Status) error
+	// LoadSnapshot returns the last complete status, nil if there is none
+	LoadSnapshot() (status *vo.Status, err error)
+	// Close open files, a closed store opens them again, when it is used
+	Close() error
+}
+
+const (
+	fileStoreFrontier = "frontier.json"
+	fileStoreResults  = "results.jsonl"
+	fileStoreSnapshot = "snapshot.json"
+)
+
+type fileStoreFrontierData struct {
+	BaseURL string
+	Paths   []string
+	Jobs    []string
+}
+
+type fileStore struct {
+	dir         string
+	resultsFile *os.File
+}
+
+// NewFileStore returns a Store, that keeps its state in dir
+func NewFileStore(dir string) (s Store, err error) {
+	errMkdir := os.MkdirAll(dir, 0755)
+	if errMkdir != nil {
+		return nil, errMkdir
+	}
+	return &fileStore{dir: dir}, nil
+}
+
+func (fs *fileStore) Load() (checkpoint *Checkpoint, err error) {
+	frontierBytes, errRead := ioutil.ReadFile(filepath.Join(fs.dir, fileStoreFrontier))
+	if os.IsNotExist(errRead) {
+		return nil, nil
+	}
+	if errRead != nil {
+		return nil, errRead
+	}
+	frontier := fileStoreFrontierData{}
+	errUnmarshal := json.Unmarshal(frontierBytes, &frontier)
+	if errUnmarshal != nil {
+		return nil, errUnmarshal
+	}
+	checkpoint = &Checkpoint{
+		BaseURL: frontier.BaseURL,
+		Paths:   frontier.Paths,
+		Jobs:    make(map[string]bool, len(frontier.Jobs)),
+		Results: map[string]vo.ScrapeResult{},
+	}
+	for _, job := range frontier.Jobs {
+		checkpoint.Jobs[job] = false
+	}
+	resultsFile, errOpen := os.Open(filepath.Join(fs.dir, fileStoreResults))
+	if os.IsNotExist(errOpen) {
+		return checkpoint, nil
+	}
+	if errOpen != nil {
+		return nil, errOpen
+	}
+	defer resultsFile.Close()
+	scanner := bufio.NewScanner(resultsFile)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		result := vo.ScrapeResult{}
+		if errUnmarshalResult := json.Unmarshal(scanner.Bytes(), &result); errUnmarshalResult != nil {
+			// most likely the last line, that was written, when we went down
+			continue
+		}
+		checkpoint.Results[result.TargetURL] = result
+	}
+	if errScan := scanner.Err(); errScan != nil {
+		return nil, errScan
+	}
+	return checkpoint, nil
+}
+
+func (fs *fileStore) SaveJobs(baseURL string, paths []string, jobs map[string]bool) error {
+	frontier := fileStoreFrontierData{
+		BaseURL: baseURL,
+		Paths:   paths,
+		Jobs:    make([]string, 0, len(jobs)),
+	}
+	for job := range jobs {
+		frontier.Jobs = append(frontier.Jobs, job)
+	}
+	frontierBytes, errMarshal := json.Marshal(frontier)
+	if errMarshal != nil {
+		return errMarshal
+	}
+	// write and rename, so that we never leave a broken frontier behind
+	filename := filepath.Join(fs.dir, fileStoreFrontier)
+	errWrite := ioutil.WriteFile(filename+".tmp", frontierBytes, 0644)
+	if errWrite != nil {
+		return errWrite
+	}
+	return os.Rename(filename+".tmp", filename)
+}
+
+func (fs *fileStore) SaveResult(result vo.ScrapeResult) error {
+	if fs.resultsFile == nil {
+		resultsFile, errOpen := os.OpenFile(filepath.Join(fs.dir, fileStoreResults), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if errOpen != nil {
+			return errOpen
+		}
+		fs.resultsFile = resultsFile
+		// do not continue a line, that was cut off
+		info, errStat := resultsFile.Stat()
+		if errStat == nil && info.Size() > 0 {
+			_, errWrite := resultsFile.Write([]byte{'\n'})
+			if errWrite != nil {
+				return errWrite
+			}
+		}
+	}
+	resultBytes, errMarshal := json.Marshal(result)
+	if errMarshal != nil {
+		return errMarshal
+	}
+	_, errWrite := fs.resultsFile.Write(append(resultBytes, '\n'))
+	return errWrite
+}
+
+func (fs *fileStore) Reset() error {
+	if fs.resultsFile != nil {
+		fs.resultsFile.Close()
+		fs.resultsFile = nil
+	}
+	for _, name := range []string{fileStoreFrontier, fileStoreResults} {
+		errRemove := os.Remove(filepath.Join(fs.dir, name))
+		if errRemove != nil && !os.IsNotExist(errRemove) {
+			return errRemove
+		}
+	}
+	return nil
+}
+
+func (fs *fileStore) Close() error {
+	if fs.resultsFile == nil {
+		return nil
+	}
+	errClose := fs.resultsFile.Close()
+	fs.resultsFile = nil
+	return errClose
+}
+
+func (fs *fileStore) SaveSnapshot(status vo.Status) error {
+	return SaveSnapshot(filepath.Join(fs.dir, fileStoreSnapshot), status)
+}
+
+func (fs *fileStore) LoadSnapshot() (status *vo.Status, err error) {
+	return LoadSnapshot(filepath.Join(fs.dir, fileStoreSnapshot))
+}
@@ -0,0 +1,233 @@
+package walker
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/foomo/walker/config"
+	"github.com/foomo/walker/vo"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStore(t *testing.T) {
+	dir := t.TempDir()
+	s, errStore := NewFileStore(dir)
+	assert.NoError(t, errStore)
+
+	cp, errLoad := s.Load()
+	assert.NoError(t, errLoad)
+	assert.Nil(t, cp)
+
+	const baseURL = "http://www.example.com"
+	assert.NoError(t, s.SaveJobs(baseURL, []string{"/"}, map[string]bool{
+		baseURL + "/":    true,
+		baseURL + "/foo": false,
+	}))
+	assert.NoError(t, s.SaveResult(vo.ScrapeResult{TargetURL: baseURL + "/", Code: 200}))
+
+	// simulate a crash in the middle of writing a result
+	resultsFile, errOpen := os.OpenFile(filepath.Join(dir, fileStoreResults), os.O_APPEND|os.O_WRONLY, 0644)
+	assert.NoError(t, errOpen)
+	_, errWrite := resultsFile.WriteString(`{"TargetURL":"http://www.exa`)
+	assert.NoError(t, errWrite)
+	resultsFile.Close()
+
+	cp, errLoad = s.Load()
+	assert.NoError(t, errLoad)
+	assert.Equal(t, baseURL, cp.BaseURL)
+	assert.Equal(t, []string{"/"}, cp.Paths)
+	assert.Equal(t, map[string]bool{baseURL + "/": false, baseURL + "/foo": false}, cp.Jobs)
+	assert.Len(t, cp.Results, 1)
+	assert.Equal(t, 200, cp.Results[baseURL+"/"].Code)
+
+	// a resumed loop keeps appending
+	resumed, _ := NewFileStore(dir)
+	assert.NoError(t, resumed.SaveResult(vo.ScrapeResult{TargetURL: baseURL + "/foo", Code: 404}))
+	cp, errLoad = resumed.Load()
+	assert.NoError(t, errLoad)
+	assert.Len(t, cp.Results, 2)
+	s = resumed
+
+	assert.NoError(t, s.Reset())
+	files, _ := ioutil.ReadDir(dir)
+	assert.Len(t, files, 0)
+	cp, errLoad = s.Load()
+	assert.NoError(t, errLoad)
+	assert.Nil(t, cp)
+}
+
+func TestFileStoreSnapshot(t *testing.T) {
+	s, errStore := NewFileStore(t.TempDir())
+	assert.NoError(t, errStore)
+	snapshot, errLoad := s.LoadSnapshot()
+	assert.NoError(t, errLoad)
+	assert.Nil(t, snapshot)
+
+	status := vo.Status{
+		Results: map[string]vo.ScrapeResult{
+			"http://www.example.com/": {TargetURL: "http://www.example.com/", Code: 200},
+		},
+		Previous: &vo.Status{},
+	}
+	assert.NoError(t, s.SaveSnapshot(status))
+	// a reset must not drop the snapshot
+	assert.NoError(t, s.Reset())
+	snapshot, errLoad = s.LoadSnapshot()
+	assert.NoError(t, errLoad)
+	assert.Equal(t, 200, snapshot.Results["http://www.example.com/"].Code)
+	assert.Nil(t, snapshot.Previous)
+}
+
+// openFiles in dir, that this process holds
+func openFiles(t *testing.T, dir string) (files []string) {
+	fds, errRead := ioutil.ReadDir("/proc/self/fd")
+	if errRead != nil {
+		t.Skip("no /proc/self/fd")
+	}
+	for _, fd := range fds {
+		target, errLink := os.Readlink(filepath.Join("/proc/self/fd", fd.Name()))
+		if errLink == nil && strings.HasPrefix(target, dir) {
+			files = append(files, target)
+		}
+	}
+	return files
+}
+
+func TestFileStoreClose(t *testing.T) {
+	dir := t.TempDir()
+	s, errStore := NewFileStore(dir)
+	require.NoError(t, errStore)
+	assert.NoError(t, s.SaveResult(vo.ScrapeResult{TargetURL: "http://www.example.com/"}))
+	assert.Len(t, openFiles(t, dir), 1)
+	assert.NoError(t, s.Close())
+	assert.Empty(t, openFiles(t, dir))
+	// a closed store can still be used
+	assert.NoError(t, s.SaveJobs("http://www.example.com", []string{"/"}, map[string]bool{}))
+	assert.NoError(t, s.SaveResult(vo.ScrapeResult{TargetURL: "http://www.example.com/foo"}))
+	cp, errLoad := s.Load()
+	assert.NoError(t, errLoad)
+	assert.Len(t, cp.Results, 2)
+	assert.NoError(t, s.Close())
+
+	// a new walk closes the store of the previous one
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body></body></html>"))
+	}))
+	defer testServer.Close()
+	w, errNew := New(WithRegisterer(prometheus.NewRegistry()))
+	require.NoError(t, errNew)
+	walk := func(stateDir string) {
+		chanLoopComplete, errWalk := w.WalkContext(context.Background(), &config.Config{
+			Target:       config.Target{BaseURL: testServer.URL, Paths: []string{"/"}},
+			IgnoreRobots: true,
+			Concurrency:  1,
+			StateDir:     stateDir,
+			Schedule:     config.Schedule{Interval: time.Hour},
+		})
+		require.NoError(t, errWalk)
+		<-chanLoopComplete
+	}
+	firstDir, secondDir := filepath.Join(dir, "first"), filepath.Join(dir, "second")
+	walk(firstDir)
+	assert.Len(t, openFiles(t, firstDir), 1)
+	walk(secondDir)
+	assert.Empty(t, openFiles(t, firstDir))
+	assert.Len(t, openFiles(t, secondDir), 1)
+	w.Stop()
+	assert.Empty(t, openFiles(t, dir))
+}
+
+func TestResume(t *testing.T) {
+	pages := map[string][]string{
+		"/":  {"/a", "/b", "/c"},
+		"/a": {},
+		"/b": {},
+		"/c": {"/d"},
+		"/d": {},
+	}
+	mutex := sync.Mutex{}
+	requests := []string{}
+	interrupted := false
+	chanInterrupt := make(chan struct{})
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requests = append(requests, r.URL.Path)
+		interrupt := r.URL.Path == "/c" && !interrupted
+		interrupted = interrupted || interrupt
+		mutex.Unlock()
+		if interrupt {
+			// the first walk dies, while it is fetching /c
+			close(chanInterrupt)
+			<-r.Context().Done()
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		html := "<html><body>"
+		for _, link := range pages[r.URL.Path] {
+			html += `<a href="` + link + `">` + link + `</a>`
+		}
+		w.Write([]byte(html + "</body></html>"))
+	}))
+	defer testServer.Close()
+	stateDir := t.TempDir()
+	conf := func() *config.Config {
+		return &config.Config{
+			Target:       config.Target{BaseURL: testServer.URL, Paths: []string{"/"}},
+			IgnoreRobots: true,
+			Concurrency:  1,
+			StateDir:     stateDir,
+			Resume:       true,
+			Once:         true,
+		}
+	}
+
+	first, errFirst := New(WithRegisterer(prometheus.NewRegistry()))
+	require.NoError(t, errFirst)
+	ctx, cancel := context.WithCancel(context.Background())
+	_, errWalk := first.WalkContext(ctx, conf())
+	require.NoError(t, errWalk)
+	select {
+	case <-chanInterrupt:
+	case <-time.After(time.Second * 10):
+		t.Fatal("the first walk did not get to /c")
+	}
+	cancel()
+	<-first.Done()
+
+	mutex.Lock()
+	assert.Equal(t, []string{"/", "/a", "/b", "/c"}, requests)
+	requests = []string{}
+	mutex.Unlock()
+
+	second, errSecond := New(WithRegisterer(prometheus.NewRegistry()))
+	require.NoError(t, errSecond)
+	defer second.Stop()
+	chanLoopComplete, errResume := second.WalkContext(context.Background(), conf())
+	require.NoError(t, errResume)
+	select {
+	case status := <-chanLoopComplete:
+		assert.Len(t, status.Results, 5)
+		for _, path := range []string{"/", "/a", "/b", "/c", "/d"} {
+			assert.Equal(t, http.StatusOK, status.Results[testServer.URL+path].Code, path)
+		}
+		assert.Equal(t, 1, status.Results[testServer.URL+"/c"].Hops)
+		assert.Equal(t, 2, status.Results[testServer.URL+"/d"].Hops)
+	case <-time.After(time.Second * 10):
+		t.Fatal("the resumed walk did not complete")
+	}
+	// pages, that were scraped before, are not fetched again
+	mutex.Lock()
+	assert.Equal(t, []string{"/c", "/d"}, requests)
+	mutex.Unlock()
+}
@@ -0,0 +1,123 @@
+package walker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/foomo/walker/config"
+	"github.com/foomo/walker/vo"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Targets runs a service with its own walker for every target of a config
+type Targets struct {
+	// in the order of the config
+	Names    []string
+	services map[string]*Service
+}
+
+// TargetStatus is a complete loop of a target
+type TargetStatus struct {
+	Name   string
+	Status vo.Status
+}
+
+func targetName(conf *config.Config) string {
+	if conf.Name == "" {
+		return DefaultName
+	}
+	return conf.Name
+}
+
+// NewTargets starts walking all targets, the metrics of the walkers are labelled with the target names.
+// chanLoopComplete is closed, when all walkers are done.
+func NewTargets(
+	ctx context.Context,
+	conf *config.Config,
+	registerer prometheus.Registerer,
+	opts ...WalkOption,
+) (t *Targets, chanLoopComplete chan TargetStatus, err error) {
+	t = &Targets{
+		services: map[string]*Service{},
+	}
+	chanLoopComplete = make(chan TargetStatus)
+	wg := sync.WaitGroup{}
+	for _, targetConf := range conf.TargetConfigs() {
+		name := targetName(targetConf)
+		w, errWalker := New(WithName(name), WithRegisterer(registerer))
+		if errWalker != nil {
+			t.stop()
+			return nil, nil, errWalker
+		}
+		s, chanServiceLoopComplete, errService := NewServiceWithWalker(ctx, w, targetConf, opts...)
+		if errService != nil {
+			w.Stop()
+			t.stop()
+			return nil, nil, errors.New("target " + name + ": " + errService.Error())
+		}
+		t.Names = append(t.Names, name)
+		t.services[name] = s
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for status := range chanServiceLoopComplete {
+				select {
+				case chanLoopComplete <- TargetStatus{Name: name, Status: status}:
+				case <-ctx.Done():
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(chanLoopComplete)
+	}()
+	return t, chanLoopComplete, nil
+}
+
+func (t *Targets) stop() {
+	for _, s := range t.services {
+		s.Walker.Stop()
+	}
+}
+
+// Get the service of a target, an empty name selects the first target
+func (t *Targets) Get(name string) (s *Service, ok bool) {
+	if name == "" && len(t.Names) > 0 {
+		name = t.Names[0]
+	}
+	s, ok = t.services[name]
+	return s, ok
+}
+
+// GetAPIHandler serves the json api of the target selected with ?target=name and lists all targets on /targets
+func (t *Targets) GetAPIHandler(basePath string) http.HandlerFunc {
+	apiHandlers := make(map[string]http.HandlerFunc, len(t.services))
+	for name, s := range t.services {
+		apiHandlers[name] = s.GetAPIHandler(basePath)
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && strings.TrimPrefix(r.URL.Path, basePath) == "/targets" {
+			statuses := make([]APIStatus, len(t.Names))
+			for i, name := range t.Names {
+				statuses[i] = t.services[name].getAPIStatus()
+				statuses[i].Target = name
+			}
+			apiReply(w, http.StatusOK, statuses)
+			return
+		}
+		name := r.URL.Query().Get("target")
+		if name == "" && len(t.Names) > 0 {
+			name = t.Names[0]
+		}
+		handler, ok := apiHandlers[name]
+		if !ok {
+			apiReplyError(w, http.StatusNotFound, errors.New("unknown target: "+name))
+			return
+		}
+		handler(w, r)
+	}
+}
@@ -0,0 +1,62 @@
+package walker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/foomo/walker/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTargets(t *testing.T) {
+	newTestServer := func(title string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><title>` + title + `</title></head><body><a href="/a">a</a></body></html>`))
+		}))
+	}
+	serverDE := newTestServer("de")
+	defer serverDE.Close()
+	serverEN := newTestServer("en")
+	defer serverEN.Close()
+	conf := &config.Config{
+		Targets: []*config.Config{
+			{Name: "de", Target: config.Target{BaseURL: serverDE.URL, Paths: []string{"/"}}, IgnoreRobots: true, Once: true, Concurrency: 1},
+			{Name: "en", Target: config.Target{BaseURL: serverEN.URL, Paths: []string{"/"}}, IgnoreRobots: true, Once: true, Concurrency: 1},
+		},
+	}
+	targets, chanLoopComplete, errTargets := NewTargets(context.Background(), conf, prometheus.NewRegistry())
+	require.NoError(t, errTargets)
+	assert.Equal(t, []string{"de", "en"}, targets.Names)
+	titles := map[string]string{}
+	for targetStatus := range chanLoopComplete {
+		titles[targetStatus.Name] = targetStatus.Status.Results[serverEN.URL+"/"].Structure.Title + targetStatus.Status.Results[serverDE.URL+"/"].Structure.Title
+		assert.Len(t, targetStatus.Status.Results, 2)
+	}
+	assert.Equal(t, map[string]string{"de": "de", "en": "en"}, titles)
+
+	handler := targets.GetAPIHandler("/api/v1")
+	get := func(path string, v interface{}) int {
+		recorder := httptest.NewRecorder()
+		handler(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		if v != nil {
+			assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), v))
+		}
+		return recorder.Code
+	}
+	statuses := []APIStatus{}
+	assert.Equal(t, http.StatusOK, get("/api/v1/targets", &statuses))
+	require.Len(t, statuses, 2)
+	assert.Equal(t, "en", statuses[1].Target)
+	status := APIStatus{}
+	assert.Equal(t, http.StatusOK, get("/api/v1/status?target=en", &status))
+	assert.Equal(t, serverEN.URL, status.TargetURL)
+	assert.Equal(t, http.StatusOK, get("/api/v1/status", &status))
+	assert.Equal(t, serverDE.URL, status.TargetURL)
+	assert.Equal(t, http.StatusNotFound, get("/api/v1/status?target=fr", nil))
+}
@@ -0,0 +1,81 @@
+package walker
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/foomo/walker/vo"
+)
+
+// requestTimer collects the phases of a request through httptrace
+type requestTimer struct {
+	mutex        sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	firstByte    time.Time
+	headers      time.Time
+	timing       vo.Timing
+}
+
+func newRequestTimer() *requestTimer {
+	return &requestTimer{start: time.Now()}
+}
+
+func (rt *requestTimer) clientTrace() *httptrace.ClientTrace {
+	lock := func(f func()) {
+		rt.mutex.Lock()
+		defer rt.mutex.Unlock()
+		f()
+	}
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			lock(func() { rt.dnsStart = time.Now() })
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			lock(func() { rt.timing.DNS += time.Since(rt.dnsStart) })
+		},
+		ConnectStart: func(network, addr string) {
+			lock(func() { rt.connectStart = time.Now() })
+		},
+		ConnectDone: func(network, addr string, err error) {
+			lock(func() { rt.timing.Connect += time.Since(rt.connectStart) })
+		},
+		TLSHandshakeStart: func() {
+			lock(func() { rt.tlsStart = time.Now() })
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			lock(func() { rt.timing.TLS += time.Since(rt.tlsStart) })
+		},
+		GotFirstResponseByte: func() {
+			// redirects will overwrite this
+			lock(func() { rt.firstByte = time.Now() })
+		},
+	}
+}
+
+// gotHeaders marks the end of client.Do
+func (rt *requestTimer) gotHeaders() {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	rt.headers = time.Now()
+}
+
+// done returns the timing, when the body has been read
+func (rt *requestTimer) done() vo.Timing {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	now := time.Now()
+	timing := rt.timing
+	if !rt.firstByte.IsZero() {
+		timing.TTFB = rt.firstByte.Sub(rt.start)
+	}
+	if !rt.headers.IsZero() {
+		timing.Body = now.Sub(rt.headers)
+	}
+	timing.Total = now.Sub(rt.start)
+	return timing
+}
@@ -0,0 +1,37 @@
+package walker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/foomo/walker/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScrapeTiming(t *testing.T) {
+	testServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><title>slow body</title>"))
+		w.(http.Flusher).Flush()
+		time.Sleep(time.Millisecond * 50)
+		w.Write([]byte("</html>"))
+	}))
+	defer testServer.Close()
+	baseURL, _ := url.Parse(testServer.URL)
+	cp := newClientPool(1, "test", false, testHTTPClientSettings(config.HTTP{}), nil)
+	cp.clients[0].client.Transport = testServer.Client().Transport
+	chanResult := make(chan scrapeResultAndClient, 1)
+	scrape(context.Background(), cp.clients[0], testServer.URL+"/", baseURL, "", nil, nil, nil, retryPolicy(config.Retry{MaxAttempts: 1}), nil, chanResult)
+	result := (<-chanResult).result
+	assert.Equal(t, http.StatusOK, result.Code)
+	timing := result.Timing
+	assert.True(t, timing.Connect > 0)
+	assert.True(t, timing.TLS > 0)
+	assert.True(t, timing.TTFB > 0)
+	assert.True(t, timing.Body >= time.Millisecond*50)
+	assert.True(t, timing.Total >= timing.TTFB+timing.Body)
+}
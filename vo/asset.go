@@ -0,0 +1,24 @@
+package vo
+
+// AssetType tells how an asset is embedded in a page
+type AssetType string
+
+const (
+	AssetTypeImage      AssetType = "image"
+	AssetTypeScript     AssetType = "script"
+	AssetTypeStylesheet AssetType = "stylesheet"
+	AssetTypeFont       AssetType = "font"
+	AssetTypePreload    AssetType = "preload"
+)
+
+// Assets of a page normalized url => type
+type Assets map[string]AssetType
+
+// AssetTypes all types of assets
+var AssetTypes = []AssetType{
+	AssetTypeImage,
+	AssetTypeScript,
+	AssetTypeStylesheet,
+	AssetTypeFont,
+	AssetTypePreload,
+}
@@ -0,0 +1,36 @@
+package vo
+
+import "time"
+
+// HistoryPage is the compact record of a result in the crawl history
+type HistoryPage struct {
+	Code     int
+	Duration time.Duration
+	Group    string
+	// htmlschema score, if the page was validated
+	Score *int `json:",omitempty"`
+}
+
+// HistoryStats aggregates a complete crawl
+type HistoryStats struct {
+	Time    time.Time
+	Results int
+	Errors  int
+	Codes   map[int]int
+	// average durations per group
+	Durations map[string]time.Duration
+	// average htmlschema scores per group
+	Scores map[string]float64
+}
+
+// HistoryEntry is a complete crawl in the history
+type HistoryEntry struct {
+	HistoryStats
+	Pages map[string]HistoryPage
+}
+
+// TrendPoint is the value of a metric in a crawl
+type TrendPoint struct {
+	Time  time.Time
+	Value float64
+}
@@ -0,0 +1,26 @@
+package vo
+
+import "time"
+
+// LinkCheck is the result of checking a link, that we do not walk
+type LinkCheck struct {
+	URL      string
+	Method   string
+	Code     int
+	Status   string
+	Error    string
+	Time     time.Time
+	Duration time.Duration
+	// -1 if unknown
+	Length       int64
+	ContentType  string
+	CacheControl string
+	Expires      string
+	ETag         string
+	LastModified string
+}
+
+// Broken tells, if the link is dead
+func (lc LinkCheck) Broken() bool {
+	return lc.Error != "" || lc.Code == 0 || lc.Code >= 400
+}
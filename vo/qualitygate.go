@@ -0,0 +1,24 @@
+package vo
+
+// QualityGateCheck is the outcome of a single threshold
+type QualityGateCheck struct {
+	Name   string
+	Limit  string
+	Value  string
+	Passed bool
+}
+
+// QualityGateResult of all thresholds
+type QualityGateResult struct {
+	Checks []QualityGateCheck
+}
+
+// Passed is true, if all checks passed
+func (r QualityGateResult) Passed() bool {
+	for _, check := range r.Checks {
+		if !check.Passed {
+			return false
+		}
+	}
+	return true
+}
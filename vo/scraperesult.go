@@ -13,25 +13,50 @@ type Redirect struct {
 	URL  string
 }
 
+// Attempt to scrape a url, that failed and was retried
+type Attempt struct {
+	Time     time.Time
+	Code     int
+	Status   string
+	Error    string
+	Duration time.Duration
+}
+
 type ScrapeResult struct {
 	// index || noindex
 	// <link rel="next" href="/damen/damentaschen/alle-taschen?page=2">
 	// <meta name="robots" content="index,follow,noodp">
 	TargetURL        string
 	Redirects        []Redirect
+	Attempts         []Attempt
 	Error            string
 	Code             int
 	ValidationReport *htmlschema.Report
-	ValidionError    error
+	ValidionError    error `json:"-"`
 	Status           string
 	ContentType      string
 	Length           int
 	Links            LinkList
 	NormalizedLinks  LinkList
-	Duration         time.Duration
-	Time             time.Time
-	Structure        Structure
-	Validations      []Validation
-	Data             interface{}
-	Group            string
+	// normalized links with a fragment like https://www.example.com/faq#shipping
+	FragmentLinks LinkList
+	// links picked by a link list filter func, they are followed again without the document, when hops change or a walk is resumed
+	FilteredLinks LinkList
+	// ids and named anchors, that can be targeted by fragments
+	Anchors []string
+	Assets  Assets
+	// until the headers arrived
+	Duration time.Duration
+	// phases of the request
+	Timing Timing
+	// certificate of https responses and certificate errors of failed requests
+	TLS         *TLSInfo
+	Time        time.Time
+	Structure   Structure
+	Validations []Validation
+	Data        interface{}
+	Group       string
+	Discovery   Discovery
+	// clicks from the start pages, -1 for pages, that were not reached by clicks like sitemap entries
+	Hops int
 }
@@ -0,0 +1,30 @@
+package vo
+
+// Discovery tells how we found a url
+type Discovery string
+
+const (
+	DiscoveryLinks   Discovery = "links"
+	DiscoverySitemap Discovery = "sitemap"
+	DiscoveryBoth    Discovery = "both"
+)
+
+// Add another way of discovering
+func (d Discovery) Add(other Discovery) Discovery {
+	switch true {
+	case d == "" || d == other:
+		return other
+	case other == "":
+		return d
+	}
+	return DiscoveryBoth
+}
+
+// SitemapEntry is an url from a sitemap
+type SitemapEntry struct {
+	Loc        string
+	Sitemap    string
+	LastMod    string
+	ChangeFreq string
+	Priority   float64
+}
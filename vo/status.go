@@ -1,12 +1,60 @@
 package vo
 
+import "time"
+
 type Status struct {
 	Results              map[string]ScrapeResult
 	Jobs                 map[string]bool
+	Sitemap              map[string]SitemapEntry
+	ExternalLinks        map[string]LinkCheck
+	Assets               map[string]LinkCheck
+	AssetMaxSizes        map[AssetType]int64
 	ScrapeSpeed          float64
 	ScrapeSpeedAverage   float64
 	ScrapeWindowRequests int64
 	ScrapeWindowSeconds  int64
 	ScrapeTotalRequests  int64
 	ScrapeTotalSeconds   int64
+	// effective requests per second, 0 means unlimited
+	ScrapeSpeedLimit float64
+	ScrapeCrawlDelay time.Duration
+	ScrapeBackoff    time.Duration
+	// no new jobs are scheduled
+	Paused bool
+	// open jobs were dropped, waiting for a new walk
+	Halted bool
+	// scheduled start of the next loop, zero while walking or without a schedule
+	NextRun time.Time
+	// normalized url => spellings of links to it => number of pages using that spelling
+	LinkVariants map[string]map[string]int
+	// why discovered links were followed or not, only recorded with ExplainLinks
+	LinkDecisions map[string]LinkDecision
+	// clicks from the start pages for jobs and results reached by clicks
+	Hops map[string]int
+	// the budget, that ended the loop early like "max pages 1000"
+	Budget string
+	// jobs, that were dropped because of the budget
+	BudgetDropped int
+	// problem with the spill file of the frontier
+	FrontierError string
+	// complete status of the loop before, to see what changed
+	Previous *Status `json:"-" yaml:"-"`
+	// stats of the crawls in the history
+	History []HistoryStats `json:"-" yaml:"-"`
+}
+
+// LinkDecision tells, why a discovered link was followed or not
+type LinkDecision struct {
+	Follow bool
+	Reason string
+}
+
+// ErrorCount counts results, that failed or returned an error status code
+func (s Status) ErrorCount() (count int) {
+	for _, r := range s.Results {
+		if r.Code == 0 || r.Code >= 400 {
+			count++
+		}
+	}
+	return count
 }
@@ -0,0 +1,18 @@
+package vo
+
+import "testing"
+
+func TestStatusErrorCount(t *testing.T) {
+	s := Status{
+		Results: map[string]ScrapeResult{
+			"/":        {Code: 200},
+			"/moved":   {Code: 301},
+			"/missing": {Code: 404},
+			"/broken":  {Code: 503},
+			"/timeout": {Code: 0, Error: "timeout"},
+		},
+	}
+	if s.ErrorCount() != 3 {
+		t.Fatal("unexpected error count", s.ErrorCount())
+	}
+}
@@ -8,6 +8,19 @@ type LinkedData struct {
 	Context string `json:"@context"`
 	Type    string ` json:"@type"`
 }
+type Hreflang struct {
+	Lang string
+	Href string
+}
+
+const (
+	ForbiddenKindLink      = "link"
+	ForbiddenKindCanonical = "canonical"
+	ForbiddenKindHreflang  = "hreflang"
+	ForbiddenKindImage     = "image"
+	ForbiddenKindScript    = "script"
+)
+
 type Structure struct {
 	Title       string
 	Description string
@@ -17,6 +30,9 @@ type Structure struct {
 	Canonical   string
 	LinkPrev    string
 	LinkNext    string
+	Hreflangs   []Hreflang
+	Images      []string
+	Scripts     []string
 	// <link rel="prev" href="/herren/herrenmode/jacken">
 	// <link rel="next" href="/herren/herrenmode/jacken?page=3">
 	// <link rel="canonical" href="https://www.globus.ch/damen/damenmode/kleider">
@@ -0,0 +1,31 @@
+package vo
+
+import "time"
+
+// Timing breaks a request down into its phases, redirects are summed up
+type Timing struct {
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+	// time to the first byte of the final response
+	TTFB time.Duration
+	// transfer of the body
+	Body time.Duration
+	// everything including the body
+	Total time.Duration
+}
+
+// TimingPhases names of the phases in a timing
+var TimingPhases = []string{"dns", "connect", "tls", "ttfb", "body", "total"}
+
+// Phases maps phase names to their durations
+func (t Timing) Phases() map[string]time.Duration {
+	return map[string]time.Duration{
+		"dns":     t.DNS,
+		"connect": t.Connect,
+		"tls":     t.TLS,
+		"ttfb":    t.TTFB,
+		"body":    t.Body,
+		"total":   t.Total,
+	}
+}
@@ -0,0 +1,14 @@
+package vo
+
+import "time"
+
+// TLSInfo describes the certificate a page was served with
+type TLSInfo struct {
+	Version  string
+	Subject  string
+	Issuer   string
+	DNSNames []string
+	NotAfter time.Time
+	// failed verifications
+	Problems []string
+}
@@ -8,6 +8,8 @@ const (
 	ValidationLevelInfo    ValidationLevel = "info"
 )
 
+const ValidationGroupForbidden = "forbidden"
+
 type Validation struct {
 	Level   ValidationLevel
 	Message string
@@ -1,28 +1,100 @@
 package walker
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"path/filepath"
 	"sort"
+	"sync"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/foomo/walker/config"
 	"github.com/foomo/walker/htmlschema"
 	"github.com/foomo/walker/reports"
 	"github.com/foomo/walker/vo"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
+// ErrWalkerDone is returned, when walking with a walker, that has terminated
+var ErrWalkerDone = errors.New("walker is done")
+
 type start struct {
+	ctx                      context.Context
 	conf                     config.Config
 	groupValidator           *htmlschema.GroupValidator
 	linkListFilterFunc       LinkListFilterFunc
 	validationFunc           ValidationFunc
 	scrapeFunc               ScrapeFunc
 	scrapeResultModifierFunc ScrapeResultModifierFunc
+	store                    Store
+	forbidden                *forbiddenMatcher
+	history                  *History
+	schedule                 *schedule
+	httpClientSettings       *httpClientSettings
+	auth                     *authenticator
+	normalizer               *urlNormalizer
+	rules                    linkRules
+	jobPriority              *jobPriority
+}
+
+// WalkOption configures the hooks of a walk
+type WalkOption func(o *walkOptions)
+
+type walkOptions struct {
+	linkListFilterFunc       LinkListFilterFunc
+	scrapeFunc               ScrapeFunc
+	validationFunc           ValidationFunc
+	scrapeResultModifierFunc ScrapeResultModifierFunc
+	priorityFunc             PriorityFunc
+}
+
+// WithLinkListFilter replaces the default link following with a custom filter
+func WithLinkListFilter(linkListFilter LinkListFilterFunc) WalkOption {
+	return func(o *walkOptions) {
+		o.linkListFilterFunc = linkListFilter
+	}
+}
+
+// WithScrapeFunc extracts custom data from every response
+func WithScrapeFunc(scrapeFunc ScrapeFunc) WalkOption {
+	return func(o *walkOptions) {
+		o.scrapeFunc = scrapeFunc
+	}
+}
+
+// WithValidationFunc validates the structure and the data of the scrape func
+func WithValidationFunc(validationFunc ValidationFunc) WalkOption {
+	return func(o *walkOptions) {
+		o.validationFunc = validationFunc
+	}
+}
+
+// WithPriorityFunc replaces the priorities of the config
+func WithPriorityFunc(priorityFunc PriorityFunc) WalkOption {
+	return func(o *walkOptions) {
+		o.priorityFunc = priorityFunc
+	}
+}
+
+// WithScrapeResultModifier modifies every scrape result, before it is stored
+func WithScrapeResultModifier(scrapeResultModifierFunc ScrapeResultModifierFunc) WalkOption {
+	return func(o *walkOptions) {
+		o.scrapeResultModifierFunc = scrapeResultModifierFunc
+	}
 }
 
+type control int
+
+const (
+	controlPause control = iota
+	controlContinue
+	controlHalt
+)
+
 type started struct {
 	Err              error
 	ChanLoopComplete chan vo.Status
@@ -47,26 +119,106 @@ type ScrapeResultModifierFunc func(result vo.ScrapeResult) (modifiedResult vo.Sc
 type ValidationFunc func(structure vo.Structure, scrapeData interface{}) (vo.Validations, error)
 
 type Walker struct {
-	chanResult     chan scrapeResultAndClient
-	chanStart      chan start
-	chanStatus     chan vo.Status
-	chanStop       chan vo.Status
-	chanStarted    chan started
-	CompleteStatus *vo.Status
+	chanResult   chan scrapeResultAndClient
+	chanStart    chan start
+	chanStatus   chan vo.Status
+	chanStop     chan vo.Status
+	chanStarted  chan started
+	chanDone     chan struct{}
+	chanControl  chan control
+	store        Store
+	name         string
+	metrics      *metrics
+	historyMutex sync.Mutex
+	history      *History
+	// the last complete loop, other goroutines read it with GetCompleteStatus
+	completeStatusMutex sync.Mutex
+	CompleteStatus      *vo.Status
 }
 
-func NewWalker() *Walker {
+// WalkerOption configures a walker
+type WalkerOption func(o *walkerOptions)
+
+type walkerOptions struct {
+	store      Store
+	registerer prometheus.Registerer
+	name       string
+}
+
+// WithStore checkpoints the frontier and the results in the given store
+func WithStore(store Store) WalkerOption {
+	return func(o *walkerOptions) {
+		o.store = store
+	}
+}
+
+// WithRegisterer registers the metrics with the given registerer instead of prometheus.DefaultRegisterer
+func WithRegisterer(registerer prometheus.Registerer) WalkerOption {
+	return func(o *walkerOptions) {
+		o.registerer = registerer
+	}
+}
+
+// WithName sets the walker label of all metrics, walkers with the same name on the same registerer share their metrics
+// and reset them, when one of them restarts. Unnamed walkers get DefaultName, DefaultName-2, ... per registerer
+func WithName(name string) WalkerOption {
+	return func(o *walkerOptions) {
+		o.name = name
+	}
+}
+
+// New returns a walker, that is idle until it is told to walk
+func New(opts ...WalkerOption) (*Walker, error) {
+	o := walkerOptions{
+		registerer: prometheus.DefaultRegisterer,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.name == "" {
+		o.name = defaultName(o.registerer)
+	}
+	m, errMetrics := setupMetrics(o.registerer, o.name)
+	if errMetrics != nil {
+		return nil, errMetrics
+	}
 	w := &Walker{
 		chanResult:  make(chan scrapeResultAndClient),
 		chanStart:   make(chan start),
 		chanStop:    make(chan vo.Status),
 		chanStatus:  make(chan vo.Status),
 		chanStarted: make(chan started),
+		chanDone:    make(chan struct{}),
+		chanControl: make(chan control),
+		store:       o.store,
+		name:        o.name,
+		metrics:     m,
 	}
 	go w.scrapeloop()
+	return w, nil
+}
+
+// NewWalker returns an unnamed walker on the default registerer
+func NewWalker() *Walker {
+	return NewWalkerWithStore(nil)
+}
+
+// NewWalkerWithStore returns a walker, that checkpoints its frontier and results in the given store
+func NewWalkerWithStore(store Store) *Walker {
+	w, err := New(WithStore(store))
+	if err != nil {
+		// only inconsistent metrics registered by someone else get us here
+		panic(err)
+	}
 	return w
 }
 
+// Name of the walker in the metrics
+func (w *Walker) Name() string {
+	return w.name
+}
+
+// Walk is WalkContext without a context and positional hooks, nil hooks are ignored
 func (w *Walker) Walk(
 	conf *config.Config,
 	linkListFilter LinkListFilterFunc,
@@ -74,6 +226,27 @@ func (w *Walker) Walk(
 	validationFunc ValidationFunc,
 	scrapeResultModifierFunc ScrapeResultModifierFunc,
 ) (chanLoopStatus chan vo.Status, err error) {
+	return w.WalkContext(
+		context.Background(),
+		conf,
+		WithLinkListFilter(linkListFilter),
+		WithScrapeFunc(scrapeFunc),
+		WithValidationFunc(validationFunc),
+		WithScrapeResultModifier(scrapeResultModifierFunc),
+	)
+}
+
+// WalkContext starts a walk, that replaces the current one. Complete loops are sent to chanLoopStatus.
+// Cancelling ctx aborts running requests, waits for the workers, closes chanLoopStatus and terminates the walker.
+func (w *Walker) WalkContext(
+	ctx context.Context,
+	conf *config.Config,
+	opts ...WalkOption,
+) (chanLoopStatus chan vo.Status, err error) {
+	o := walkOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
 	var groupValidator *htmlschema.GroupValidator
 	if conf.SchemaRoot != "" {
 		gv, errGroupValidator := htmlschema.NewGroupValidator(conf.SchemaRoot)
@@ -82,26 +255,169 @@ func (w *Walker) Walk(
 		}
 		groupValidator = gv
 	}
-	w.chanStart <- start{
+	var forbidden *forbiddenMatcher
+	if len(conf.Forbidden) > 0 {
+		fm, errForbidden := newForbiddenMatcher(conf.Forbidden)
+		if errForbidden != nil {
+			return nil, errForbidden
+		}
+		forbidden = fm
+	}
+	store := w.store
+	if store == nil && conf.StateDir != "" {
+		fileStore, errStore := NewFileStore(conf.StateDir)
+		if errStore != nil {
+			return nil, errStore
+		}
+		store = fileStore
+	}
+	var history *History
+	if conf.History.Keep > 0 {
+		historyDir := conf.History.Dir
+		if historyDir == "" && conf.StateDir != "" {
+			historyDir = filepath.Join(conf.StateDir, "history")
+		}
+		if historyDir == "" {
+			return nil, errors.New("the history needs a dir or a statedir")
+		}
+		h, errHistory := NewHistory(historyDir, conf.History.Keep)
+		if errHistory != nil {
+			return nil, errHistory
+		}
+		history = h
+	}
+	sched, errSchedule := newSchedule(conf.Schedule)
+	if errSchedule != nil {
+		return nil, errSchedule
+	}
+	settings, errSettings := newHTTPClientSettings(conf.HTTP, conf.Target.BaseURL)
+	if errSettings != nil {
+		return nil, errSettings
+	}
+	auth, errAuth := newAuthenticator(conf.Auth, conf.Target.BaseURL)
+	if errAuth != nil {
+		return nil, errAuth
+	}
+	normalizer, errNormalizer := newURLNormalizer(conf.Normalize)
+	if errNormalizer != nil {
+		return nil, errNormalizer
+	}
+	rules, errRules := newLinkRules(conf.Rules)
+	if errRules != nil {
+		return nil, errRules
+	}
+	priority, errPriority := newJobPriority(conf.Priority, o.priorityFunc)
+	if errPriority != nil {
+		return nil, errPriority
+	}
+	errQualityGate := validateQualityGate(conf.QualityGate)
+	if errQualityGate != nil {
+		return nil, errQualityGate
+	}
+	w.historyMutex.Lock()
+	w.history = history
+	w.historyMutex.Unlock()
+	select {
+	case w.chanStart <- start{
+		ctx:                      ctx,
 		groupValidator:           groupValidator,
 		conf:                     *conf,
-		scrapeFunc:               scrapeFunc,
-		linkListFilterFunc:       linkListFilter,
-		validationFunc:           validationFunc,
-		scrapeResultModifierFunc: scrapeResultModifierFunc,
+		scrapeFunc:               o.scrapeFunc,
+		linkListFilterFunc:       o.linkListFilterFunc,
+		validationFunc:           o.validationFunc,
+		scrapeResultModifierFunc: o.scrapeResultModifierFunc,
+		store:                    store,
+		forbidden:                forbidden,
+		history:                  history,
+		schedule:                 sched,
+		httpClientSettings:       settings,
+		auth:                     auth,
+		normalizer:               normalizer,
+		rules:                    rules,
+		jobPriority:              priority,
+	}:
+	case <-w.chanDone:
+		return nil, ErrWalkerDone
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 	st := <-w.chanStarted
 	return st.ChanLoopComplete, st.Err
 }
 
+// Stop aborts running requests, waits for the workers and terminates the walker
 func (w *Walker) Stop() vo.Status {
-	w.chanStop <- vo.Status{}
-	return <-w.chanStop
+	select {
+	case w.chanStop <- vo.Status{}:
+		return <-w.chanStop
+	case <-w.chanDone:
+		return w.doneStatus()
+	}
 }
 
 func (w *Walker) GetStatus() vo.Status {
-	w.chanStatus <- vo.Status{}
-	return <-w.chanStatus
+	select {
+	case w.chanStatus <- vo.Status{}:
+		return <-w.chanStatus
+	case <-w.chanDone:
+		return w.doneStatus()
+	}
+}
+
+func (w *Walker) control(c control) error {
+	select {
+	case w.chanControl <- c:
+		return nil
+	case <-w.chanDone:
+		return ErrWalkerDone
+	}
+}
+
+// Pause stops scheduling new jobs, running scrapes will complete
+func (w *Walker) Pause() error {
+	return w.control(controlPause)
+}
+
+// Continue a paused or halted walk
+func (w *Walker) Continue() error {
+	return w.control(controlContinue)
+}
+
+// Halt drops all open jobs and stays idle until the next Walk, unlike Stop the scrape loop keeps running
+func (w *Walker) Halt() error {
+	return w.control(controlHalt)
+}
+
+// GetHistory returns the history of complete crawls, nil if there is none
+func (w *Walker) GetHistory() *History {
+	w.historyMutex.Lock()
+	defer w.historyMutex.Unlock()
+	return w.history
+}
+
+// Done is closed, when the scrape loop has terminated
+func (w *Walker) Done() <-chan struct{} {
+	return w.chanDone
+}
+
+// GetCompleteStatus returns the status of the last complete loop, nil if there is none yet
+func (w *Walker) GetCompleteStatus() *vo.Status {
+	w.completeStatusMutex.Lock()
+	defer w.completeStatusMutex.Unlock()
+	return w.CompleteStatus
+}
+
+func (w *Walker) setCompleteStatus(status *vo.Status) {
+	w.completeStatusMutex.Lock()
+	defer w.completeStatusMutex.Unlock()
+	w.CompleteStatus = status
+}
+
+func (w *Walker) doneStatus() vo.Status {
+	if completeStatus := w.GetCompleteStatus(); completeStatus != nil {
+		return *completeStatus
+	}
+	return vo.Status{}
 }
 
 func line(w io.Writer) {
@@ -118,6 +434,6 @@ func (wlkr *Walker) GetReportHandler(basePath string) http.HandlerFunc {
 	h := reports.GetReportHandler(basePath)
 	return func(w http.ResponseWriter, r *http.Request) {
 		runningStatus := wlkr.GetStatus()
-		h(w, r, wlkr.CompleteStatus, &runningStatus)
+		h(w, r, wlkr.GetCompleteStatus(), &runningStatus)
 	}
 }
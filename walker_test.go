@@ -1,7 +1,9 @@
 package walker
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"net/http/httptest"
 	"path/filepath"
 	"runtime"
@@ -11,7 +13,9 @@ import (
 	"github.com/davecgh/go-spew/spew"
 	"github.com/foomo/walker/config"
 	"github.com/foomo/walker/htmlschema/example"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func getExampleDir(path ...string) string {
@@ -81,3 +85,114 @@ StatusLoop:
 		}
 	}
 }
+
+func TestWalkerOnce(t *testing.T) {
+	s := example.NewServer(getExampleDir("htmlschema", "example", "htdocs"))
+	testServer := httptest.NewServer(s)
+	defer testServer.Close()
+	w, errNew := New(WithRegisterer(prometheus.NewRegistry()))
+	require.NoError(t, errNew)
+	chanStatus, errWalk := w.WalkContext(context.Background(), &config.Config{
+		Target:       config.Target{BaseURL: testServer.URL, Paths: []string{"/"}},
+		GroupHeader:  example.GroupHeader,
+		IgnoreRobots: true,
+		Once:         true,
+		Concurrency:  1,
+	})
+	require.NoError(t, errWalk)
+	select {
+	case status, open := <-chanStatus:
+		require.True(t, open, "a single pass walk must complete its loop")
+		assert.NotEmpty(t, status.Results)
+		_, open = <-chanStatus
+		assert.False(t, open, "a single pass walk must close the loop channel after one loop")
+		select {
+		case <-w.Done():
+		case <-time.After(time.Second * 5):
+			t.Fatal("the walker did not terminate after one loop")
+		}
+		assert.Equal(t, len(status.Results), len(w.GetStatus().Results))
+	case <-time.After(time.Second * 10):
+		t.Fatal("the single pass walk did not complete")
+	}
+}
+
+func TestWalkContextCancel(t *testing.T) {
+	chanRequested := make(chan struct{}, 1)
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case chanRequested <- struct{}{}:
+		default:
+		}
+		// hang until the client gives up
+		<-r.Context().Done()
+	}))
+	defer testServer.Close()
+	w, errNew := New(WithRegisterer(prometheus.NewRegistry()))
+	require.NoError(t, errNew)
+	ctx, cancel := context.WithCancel(context.Background())
+	chanLoopComplete, errWalk := w.WalkContext(ctx, &config.Config{
+		Target:       config.Target{BaseURL: testServer.URL, Paths: []string{"/"}},
+		IgnoreRobots: true,
+		Concurrency:  1,
+	})
+	require.NoError(t, errWalk)
+	<-chanRequested
+	cancel()
+	select {
+	case _, open := <-chanLoopComplete:
+		assert.False(t, open, "cancelling must close the loop channel")
+	case <-time.After(time.Second * 5):
+		t.Fatal("the loop channel was not closed")
+	}
+	<-w.Done()
+	_, errWalkAgain := w.WalkContext(context.Background(), &config.Config{})
+	assert.Equal(t, ErrWalkerDone, errWalkAgain)
+}
+
+func TestLoopCompleteNotTaken(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body></body></html>"))
+	}))
+	defer testServer.Close()
+	w, errNew := New(WithRegisterer(prometheus.NewRegistry()))
+	require.NoError(t, errNew)
+	defer w.Stop()
+	newConf := func() *config.Config {
+		return &config.Config{
+			Target:       config.Target{BaseURL: testServer.URL, Paths: []string{"/"}},
+			IgnoreRobots: true,
+			Concurrency:  1,
+		}
+	}
+	// nobody reads the loops of the first walk
+	first, errFirst := w.WalkContext(context.Background(), newConf())
+	require.NoError(t, errFirst)
+	for w.GetCompleteStatus() == nil {
+		time.Sleep(time.Millisecond * 10)
+	}
+	chanControlled := make(chan error)
+	go func() {
+		chanControlled <- w.Pause()
+	}()
+	select {
+	case errPause := <-chanControlled:
+		assert.NoError(t, errPause)
+	case <-time.After(time.Second * 5):
+		t.Fatal("pausing blocked on the loop hand off")
+	}
+	chanStarted := make(chan error)
+	go func() {
+		_, errSecond := w.WalkContext(context.Background(), newConf())
+		chanStarted <- errSecond
+	}()
+	select {
+	case errSecond := <-chanStarted:
+		assert.NoError(t, errSecond)
+	case <-time.After(time.Second * 5):
+		t.Fatal("starting blocked on the loop hand off")
+	}
+	_, open := <-first
+	assert.False(t, open, "the new walk must close the loop channel of the first one")
+}
@@ -26,6 +26,17 @@ func (w *Walker) PrintStatus(writer io.Writer, status vo.Status) {
 		" scrape average: ", status.ScrapeTotalRequests, status.ScrapeTotalSeconds,
 		", scapespeed: ", status.ScrapeSpeedAverage, "requests/s",
 	)
+	headline(writer,
+		" politeness: limit ", status.ScrapeSpeedLimit, "requests/s (0 is unlimited)",
+		", crawl delay: ", status.ScrapeCrawlDelay,
+		", backoff: ", status.ScrapeBackoff,
+	)
+	if !status.NextRun.IsZero() {
+		headline(writer, " next loop: ", status.NextRun)
+	}
+	if status.FrontierError != "" {
+		headline(writer, " frontier: ", status.FrontierError)
+	}
 
 	reports.ReportSummaryBody(status, writer, nil)
 